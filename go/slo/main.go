@@ -3,9 +3,10 @@ package main
 import (
 	"github.com/blamelesshq/blameless-examples/slo/packages/cmd"
 	"github.com/blamelesshq/blameless-examples/slo/packages/config"
+	"github.com/blamelesshq/blameless-examples/slo/packages/logging"
 )
 
 func main() {
-	config.Environment()
+	logging.Setup(config.Environment().Log)
 	cmd.Execute()
 }
@@ -0,0 +1,214 @@
+package budget
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+	"github.com/blamelesshq/blameless-examples/slo/packages/utils"
+)
+
+// Options describe the SLO raw data is measured against
+type Options struct {
+	SliType     string
+	Objective   float64 // Percentage of good events, e.g. 99.9
+	Threshold   float64 // Latency SLOs only, a slice is good at or under it
+	From        time.Time
+	To          time.Time
+	BurnWindows []Window
+	Forecast    *ForecastOptions // Projects when the budget runs out when set
+}
+
+// Window is a named lookback such as 1h or 3d
+type Window struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Sample is one step of an SLI's metric path as Prometheus returns it, before ingest rounds
+// it. Availability samples count good and valid requests, latency samples hold a latency.
+type Sample struct {
+	Start   int64
+	Good    float64
+	Valid   float64
+	Latency float64
+}
+
+type BurnRate struct {
+	Window     string  `json:"window"`
+	Good       float64 `json:"good"`
+	Valid      float64 `json:"valid"`
+	ErrorRatio float64 `json:"errorRatio"`
+	Rate       float64 `json:"rate"` // How many times faster than the objective allows the budget is spent
+}
+
+// Status is where an SLO stands over its window. Without valid events nothing is
+// known, Valid is 0 and the budget is reported untouched.
+type Status struct {
+	From            time.Time   `json:"from"`
+	To              time.Time   `json:"to"`
+	Objective       float64     `json:"objectivePercentage"`
+	Good            float64     `json:"good"`
+	Valid           float64     `json:"valid"`
+	Achieved        float64     `json:"achievedPercentage"`
+	AllowedErrors   float64     `json:"allowedErrors"` // Bad events the objective allows over the valid events so far
+	BudgetConsumed  float64     `json:"budgetConsumedPercentage"`
+	BudgetRemaining float64     `json:"budgetRemainingPercentage"` // Negative once the budget is exhausted
+	BurnRates       []*BurnRate `json:"burnRates"`
+	Forecast        *Forecast   `json:"forecast,omitempty"`
+}
+
+// ParseWindow reads a lookback such as 30m, 6h, 1d or 2w
+func ParseWindow(s string) (Window, error) {
+	d, err := utils.ParseDuration(s)
+	if err != nil {
+		return Window{}, err
+	}
+	return Window{Name: s, Duration: d}, nil
+}
+
+func ParseWindows(names []string) ([]Window, error) {
+	windows := make([]Window, len(names))
+	for i, name := range names {
+		w, err := ParseWindow(name)
+		if err != nil {
+			return nil, err
+		}
+		windows[i] = w
+	}
+	return windows, nil
+}
+
+// SloWindow returns the range an SLO window covers at now. Rolling windows end at now,
+// calendar windows start at the beginning of the current week, month or quarter in UTC.
+func SloWindow(w *models.SloWindow, now time.Time) (time.Time, time.Time, error) {
+	if w == nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("the SLO has no window")
+	}
+	if w.Type == models.WindowTypes.Rolling {
+		return now.AddDate(0, 0, -w.Days), now, nil
+	}
+	utc := now.UTC()
+	day := time.Date(utc.Year(), utc.Month(), utc.Day(), 0, 0, 0, 0, time.UTC)
+	switch w.Unit {
+	case "week":
+		// Weeks start on Monday
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset), now, nil
+	case "month":
+		return time.Date(utc.Year(), utc.Month(), 1, 0, 0, 0, 0, time.UTC), now, nil
+	case "quarter":
+		month := time.Month((int(utc.Month())-1)/3*3 + 1)
+		return time.Date(utc.Year(), month, 1, 0, 0, 0, 0, time.UTC), now, nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("unknown calendar window unit %q", w.Unit)
+}
+
+// SloWindowEnd is when the current SLO window closes, now for rolling windows
+func SloWindowEnd(w *models.SloWindow, now time.Time) time.Time {
+	if w == nil || w.Type == models.WindowTypes.Rolling {
+		return now
+	}
+	start, _, err := SloWindow(w, now)
+	if err != nil {
+		return now
+	}
+	switch w.Unit {
+	case "week":
+		return start.AddDate(0, 0, 7)
+	case "month":
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 3, 0)
+}
+
+// SloWindowLength is how long the SLO window is in total, the whole week, month or
+// quarter of a calendar window at now
+func SloWindowLength(w *models.SloWindow, now time.Time) time.Duration {
+	if w == nil {
+		return 0
+	}
+	if w.Type == models.WindowTypes.Rolling {
+		return time.Duration(w.Days) * 24 * time.Hour
+	}
+	start, _, err := SloWindow(w, now)
+	if err != nil {
+		return 0
+	}
+	return SloWindowEnd(w, now).Sub(start)
+}
+
+// Compute measures samples against an SLO. Availability samples count good and valid
+// requests, latency samples count as a slice that is good when its latency is at or
+// under the threshold. Samples outside From and To are ignored.
+func Compute(data []Sample, opts Options) (*Status, error) {
+	if opts.Objective <= 0 || opts.Objective >= 100 {
+		return nil, fmt.Errorf("objective percentage must be between 0 and 100, got %g", opts.Objective)
+	}
+	if opts.SliType == models.Types.Latency && opts.Threshold <= 0 {
+		return nil, fmt.Errorf("a latency SLO needs a threshold above 0")
+	}
+	if opts.SliType != models.Types.Latency && opts.SliType != models.Types.Availability {
+		return nil, fmt.Errorf("error budgets are computed for availability and latency SLIs, not %s", opts.SliType)
+	}
+
+	allowed := 1 - opts.Objective/100
+	s := &Status{
+		From:      opts.From,
+		To:        opts.To,
+		Objective: opts.Objective,
+		BurnRates: []*BurnRate{},
+	}
+	s.Good, s.Valid = count(data, opts, opts.From, opts.To)
+	s.AllowedErrors = s.Valid * allowed
+	s.Achieved = 100
+	if s.Valid > 0 {
+		s.Achieved = s.Good / s.Valid * 100
+		s.BudgetConsumed = errorRatio(s.Good, s.Valid) / allowed * 100
+	}
+	s.BudgetRemaining = 100 - s.BudgetConsumed
+
+	for _, w := range opts.BurnWindows {
+		from := opts.To.Add(-w.Duration)
+		if from.Before(opts.From) {
+			from = opts.From
+		}
+		good, valid := count(data, opts, from, opts.To)
+		b := &BurnRate{Window: w.Name, Good: good, Valid: valid}
+		if valid > 0 {
+			b.ErrorRatio = errorRatio(good, valid)
+			b.Rate = b.ErrorRatio / allowed
+		}
+		s.BurnRates = append(s.BurnRates, b)
+	}
+	if opts.Forecast != nil {
+		s.Forecast = forecast(data, opts, s)
+	}
+	return s, nil
+}
+
+// count sums good and valid events of samples starting in [from, to)
+func count(data []Sample, opts Options, from time.Time, to time.Time) (float64, float64) {
+	start, end := from.Unix(), to.Unix()
+	good, valid := 0.0, 0.0
+	for _, d := range data {
+		if d.Start < start || d.Start >= end {
+			continue
+		}
+		if opts.SliType == models.Types.Latency {
+			valid++
+			if d.Latency <= opts.Threshold {
+				good++
+			}
+			continue
+		}
+		good += d.Good
+		valid += d.Valid
+	}
+	return good, valid
+}
+
+func errorRatio(good float64, valid float64) float64 {
+	return math.Max(0, valid-good) / valid
+}
@@ -0,0 +1,241 @@
+package budget
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+)
+
+var (
+	to   = time.Unix(100*3600, 0)
+	from = to.Add(-24 * time.Hour)
+)
+
+// at is the unix start of a sample the given duration before to
+func at(before time.Duration) int64 {
+	return to.Add(-before).Unix()
+}
+
+func requests(start int64, good float64, valid float64) Sample {
+	return Sample{Start: start, Good: good, Valid: valid}
+}
+
+func latency(start int64, value float64) Sample {
+	return Sample{Start: start, Latency: value}
+}
+
+func approx(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      Options
+		data      []Sample
+		good      float64
+		valid     float64
+		achieved  float64
+		consumed  float64
+		burnRates []BurnRate
+	}{
+		{
+			name:     "availability",
+			opts:     Options{SliType: models.Types.Availability, Objective: 90},
+			data:     []Sample{requests(at(2*time.Hour), 95, 100), requests(at(time.Hour), 90, 100)},
+			good:     185,
+			valid:    200,
+			achieved: 92.5,
+			consumed: 75,
+		},
+		{
+			name:     "latency slices at the threshold are good",
+			opts:     Options{SliType: models.Types.Latency, Objective: 50, Threshold: 200},
+			data:     []Sample{latency(at(4*time.Minute), 100), latency(at(3*time.Minute), 300), latency(at(2*time.Minute), 200), latency(at(time.Minute), 150)},
+			good:     3,
+			valid:    4,
+			achieved: 75,
+			consumed: 50,
+		},
+		{
+			name:     "fractional request rates are not rounded",
+			opts:     Options{SliType: models.Types.Availability, Objective: 99},
+			data:     []Sample{requests(at(2*time.Hour), 0.2, 0.2), requests(at(time.Hour), 0.4, 0.6), requests(at(30*time.Minute), 0, 0.2)},
+			good:     0.6,
+			valid:    1,
+			achieved: 60,
+			consumed: 4000,
+		},
+		{
+			name:     "fractional latencies are compared unrounded",
+			opts:     Options{SliType: models.Types.Latency, Objective: 50, Threshold: 0.25},
+			data:     []Sample{latency(at(4*time.Minute), 0.2), latency(at(3*time.Minute), 0.25), latency(at(2*time.Minute), 0.251), latency(at(time.Minute), 0)},
+			good:     3,
+			valid:    4,
+			achieved: 75,
+			consumed: 50,
+		},
+		{
+			name:     "no valid events leave the budget untouched",
+			opts:     Options{SliType: models.Types.Availability, Objective: 99},
+			achieved: 100,
+		},
+		{
+			name:     "samples outside the window are ignored",
+			opts:     Options{SliType: models.Types.Availability, Objective: 90},
+			data:     []Sample{requests(at(25*time.Hour), 0, 100), requests(to.Unix(), 0, 100), requests(at(time.Hour), 100, 100)},
+			good:     100,
+			valid:    100,
+			achieved: 100,
+		},
+		{
+			name:     "more good than valid events consume nothing",
+			opts:     Options{SliType: models.Types.Availability, Objective: 90},
+			data:     []Sample{requests(at(time.Hour), 110, 100)},
+			good:     110,
+			valid:    100,
+			achieved: 110,
+		},
+		{
+			name: "burn rates over windows",
+			opts: Options{
+				SliType:     models.Types.Availability,
+				Objective:   90,
+				BurnWindows: []Window{{Name: "1h", Duration: time.Hour}, {Name: "2d", Duration: 48 * time.Hour}},
+			},
+			data:     []Sample{requests(at(10*time.Hour), 100, 100), requests(at(30*time.Minute), 80, 100)},
+			good:     180,
+			valid:    200,
+			achieved: 90,
+			consumed: 100,
+			burnRates: []BurnRate{
+				{Window: "1h", Good: 80, Valid: 100, ErrorRatio: 0.2, Rate: 2},
+				{Window: "2d", Good: 180, Valid: 200, ErrorRatio: 0.1, Rate: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.From, tt.opts.To = from, to
+			s, err := Compute(tt.data, tt.opts)
+			if err != nil {
+				t.Fatalf("Compute() error = %v", err)
+			}
+			if !approx(s.Good, tt.good) || !approx(s.Valid, tt.valid) {
+				t.Errorf("Good, Valid = %g, %g, want %g, %g", s.Good, s.Valid, tt.good, tt.valid)
+			}
+			if !approx(s.Achieved, tt.achieved) {
+				t.Errorf("Achieved = %g, want %g", s.Achieved, tt.achieved)
+			}
+			if !approx(s.BudgetConsumed, tt.consumed) || !approx(s.BudgetRemaining, 100-tt.consumed) {
+				t.Errorf("BudgetConsumed, BudgetRemaining = %g, %g, want %g, %g", s.BudgetConsumed, s.BudgetRemaining, tt.consumed, 100-tt.consumed)
+			}
+			if len(s.BurnRates) != len(tt.burnRates) {
+				t.Fatalf("BurnRates = %d, want %d", len(s.BurnRates), len(tt.burnRates))
+			}
+			for i, want := range tt.burnRates {
+				got := s.BurnRates[i]
+				if got.Window != want.Window || !approx(got.Good, want.Good) || !approx(got.Valid, want.Valid) ||
+					!approx(got.ErrorRatio, want.ErrorRatio) || !approx(got.Rate, want.Rate) {
+					t.Errorf("BurnRates[%d] = %+v, want %+v", i, *got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestComputeInvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"objective of 100", Options{SliType: models.Types.Availability, Objective: 100}},
+		{"objective of 0", Options{SliType: models.Types.Availability}},
+		{"latency without a threshold", Options{SliType: models.Types.Latency, Objective: 99}},
+		{"unsupported sli type", Options{SliType: models.Types.Throughput, Objective: 99}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Compute(nil, tt.opts); err == nil {
+				t.Error("Compute() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestSloWindow(t *testing.T) {
+	// A Thursday
+	now := time.Date(2024, time.May, 16, 15, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		window *models.SloWindow
+		start  time.Time
+		end    time.Time
+		length time.Duration
+	}{
+		{
+			name:   "rolling",
+			window: &models.SloWindow{Type: models.WindowTypes.Rolling, Days: 28},
+			start:  now.AddDate(0, 0, -28),
+			end:    now,
+			length: 28 * 24 * time.Hour,
+		},
+		{
+			name:   "calendar week starts on monday",
+			window: &models.SloWindow{Type: models.WindowTypes.Calendar, Unit: "week"},
+			start:  time.Date(2024, time.May, 13, 0, 0, 0, 0, time.UTC),
+			end:    time.Date(2024, time.May, 20, 0, 0, 0, 0, time.UTC),
+			length: 7 * 24 * time.Hour,
+		},
+		{
+			name:   "calendar month",
+			window: &models.SloWindow{Type: models.WindowTypes.Calendar, Unit: "month"},
+			start:  time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC),
+			end:    time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC),
+			length: 31 * 24 * time.Hour,
+		},
+		{
+			name:   "calendar quarter",
+			window: &models.SloWindow{Type: models.WindowTypes.Calendar, Unit: "quarter"},
+			start:  time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC),
+			end:    time.Date(2024, time.July, 1, 0, 0, 0, 0, time.UTC),
+			length: 91 * 24 * time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := SloWindow(tt.window, now)
+			if err != nil {
+				t.Fatalf("SloWindow() error = %v", err)
+			}
+			if !start.Equal(tt.start) || !end.Equal(now) {
+				t.Errorf("SloWindow() = %v, %v, want %v, %v", start, end, tt.start, now)
+			}
+			if got := SloWindowEnd(tt.window, now); !got.Equal(tt.end) {
+				t.Errorf("SloWindowEnd() = %v, want %v", got, tt.end)
+			}
+			if got := SloWindowLength(tt.window, now); got != tt.length {
+				t.Errorf("SloWindowLength() = %v, want %v", got, tt.length)
+			}
+		})
+	}
+}
+
+func TestSloWindowInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		window *models.SloWindow
+	}{
+		{"no window", nil},
+		{"unknown unit", &models.SloWindow{Type: models.WindowTypes.Calendar, Unit: "year"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := SloWindow(tt.window, time.Now()); err == nil {
+				t.Error("SloWindow() error = nil, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package budget
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/clients"
+	"github.com/blamelesshq/blameless-examples/slo/packages/ingest"
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+)
+
+// Fetch queries the samples of an SLI starting in [from, to), a day at a time to stay
+// under the points Prometheus returns per query. Values are kept as Prometheus returns
+// them, samples without a value are skipped as ingest skips them.
+func Fetch(p *clients.PrometheusClient, sli *models.SliBody, sliType string, from time.Time, to time.Time) ([]Sample, error) {
+	mp, err := sli.DecodeMetricPath()
+	if err != nil {
+		return nil, err
+	}
+	samples := []Sample{}
+	for _, w := range ingest.Windows(from, to, 24*time.Hour) {
+		var chunk []Sample
+		switch sliType {
+		case models.Types.Availability:
+			if mp.Availability == nil {
+				return nil, fmt.Errorf("availability sli %d has no good and valid queries", sli.Id)
+			}
+			chunk, err = availabilitySamples(p, mp.Availability, w)
+		case models.Types.Latency:
+			if mp.Latency == "" {
+				return nil, fmt.Errorf("latency sli %d has no query", sli.Id)
+			}
+			chunk, err = latencySamples(p, mp.Latency, w)
+		default:
+			return nil, fmt.Errorf("error budgets are computed for availability and latency SLIs, not %s", sliType)
+		}
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, chunk...)
+	}
+	return samples, nil
+}
+
+func latencySamples(p *clients.PrometheusClient, query string, w ingest.Window) ([]Sample, error) {
+	values, err := queryValues(p, query, w)
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]Sample, len(values))
+	for i, v := range values {
+		samples[i] = Sample{Start: v.start, Latency: v.value}
+	}
+	return samples, nil
+}
+
+// availabilitySamples merges good and valid requests by timestamp, a timestamp needs both
+func availabilitySamples(p *clients.PrometheusClient, queries *models.AvailabilityStruct, w ingest.Window) ([]Sample, error) {
+	good, err := queryValues(p, queries.GoodRequest, w)
+	if err != nil {
+		return nil, err
+	}
+	valid, err := queryValues(p, queries.ValidRequest, w)
+	if err != nil {
+		return nil, err
+	}
+	goodByStart := map[int64]float64{}
+	for _, v := range good {
+		goodByStart[v.start] = v.value
+	}
+	samples := make([]Sample, 0, len(valid))
+	for _, v := range valid {
+		if g, ok := goodByStart[v.start]; ok {
+			samples = append(samples, Sample{Start: v.start, Good: g, Valid: v.value})
+		}
+	}
+	return samples, nil
+}
+
+type value struct {
+	start int64
+	value float64
+}
+
+// queryValues returns the values of a query in time order. Prometheus range queries
+// include their end, the sample at w.To belongs to the next window and is dropped.
+func queryValues(p *clients.PrometheusClient, query string, w ingest.Window) ([]value, error) {
+	series, err := p.QueryRangeSeries(query, w.From, w.To)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(series) == 0:
+		return nil, nil
+	case len(series) > 1:
+		return nil, fmt.Errorf("query %q returned %d series, aggregate it to one such as sum(...)", query, len(series))
+	}
+	values := make([]value, 0, len(series[0].Values))
+	for _, v := range series[0].Values {
+		f, err := strconv.ParseFloat(v.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("sample %q of query %q is not a number", v.Value, query)
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) || int64(v.Time) >= w.To.Unix() {
+			continue
+		}
+		values = append(values, value{start: int64(v.Time), value: f})
+	}
+	return values, nil
+}
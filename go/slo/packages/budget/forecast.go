@@ -0,0 +1,161 @@
+package budget
+
+import (
+	"math"
+	"time"
+)
+
+var Methods = &ForecastMethods{
+	Linear: "linear",
+	Ewma:   "ewma",
+}
+
+type ForecastMethods struct {
+	Linear string
+	Ewma   string
+}
+
+// confidenceZ is the z-score of the 95% confidence bands
+const confidenceZ = 1.96
+
+// ForecastOptions describe how budget exhaustion is projected from recent burn rates
+type ForecastOptions struct {
+	Lookback time.Duration // Recent range burn rates are read from
+	Bucket   time.Duration // Burn rates are measured per bucket, an hour by default
+	Alpha    float64       // Weight of the latest bucket in the exponentially weighted rate
+	Length   time.Duration // Length of the SLO window, a burn rate of 1 spends the budget over it
+	End      time.Time     // A budget exhausted before End is a breach
+}
+
+// Projection is when the remaining budget runs out if it keeps burning at BurnRate.
+// The earliest and latest times are those of the upper and lower confidence bands, a
+// nil time means the budget is not spent at that rate.
+type Projection struct {
+	Method       string     `json:"method"`
+	BurnRate     float64    `json:"burnRate"`
+	BurnRateLow  float64    `json:"burnRateLow"`
+	BurnRateHigh float64    `json:"burnRateHigh"`
+	ExhaustsAt   *time.Time `json:"exhaustsAt"`
+	Earliest     *time.Time `json:"earliestExhaustsAt"`
+	Latest       *time.Time `json:"latestExhaustsAt"`
+	Breach       bool       `json:"breach"`
+}
+
+// Forecast projects budget exhaustion with every method. Breach is set when any
+// method runs out of budget before the end of the window.
+type Forecast struct {
+	From        time.Time     `json:"from"`
+	End         time.Time     `json:"end"`
+	Buckets     int           `json:"buckets"` // Buckets with valid events the rates are read from
+	Projections []*Projection `json:"projections"`
+	Breach      bool          `json:"breach"`
+}
+
+func forecast(data []Sample, opts Options, s *Status) *Forecast {
+	f := opts.Forecast
+	bucket := f.Bucket
+	if bucket <= 0 {
+		bucket = time.Hour
+	}
+	from := opts.To.Add(-f.Lookback)
+	if from.Before(opts.From) {
+		from = opts.From
+	}
+	allowed := 1 - opts.Objective/100
+
+	// Burn rate of each bucket in time order, buckets without valid events are skipped
+	rates := []float64{}
+	good, valid := 0.0, 0.0
+	for start := from; start.Before(opts.To); start = start.Add(bucket) {
+		end := start.Add(bucket)
+		if end.After(opts.To) {
+			end = opts.To
+		}
+		g, v := count(data, opts, start, end)
+		if v == 0 {
+			continue
+		}
+		good += g
+		valid += v
+		rates = append(rates, errorRatio(g, v)/allowed)
+	}
+
+	fc := &Forecast{From: from, End: f.End, Buckets: len(rates), Projections: []*Projection{}}
+	if len(rates) == 0 {
+		return fc
+	}
+
+	// The linear rate weighs buckets by their events, its band is the standard error of the bucket rates
+	linear := errorRatio(good, valid) / allowed
+	linearBand := confidenceZ * stddev(rates) / math.Sqrt(float64(len(rates)))
+
+	ewma, variance := rates[0], 0.0
+	for _, r := range rates[1:] {
+		d := r - ewma
+		ewma += f.Alpha * d
+		variance = (1 - f.Alpha) * (variance + f.Alpha*d*d)
+	}
+	ewmaBand := confidenceZ * math.Sqrt(variance)
+
+	remaining := 1.0
+	if s.Valid > 0 {
+		remaining = s.BudgetRemaining / 100
+	}
+	for _, p := range []*Projection{
+		project(Methods.Linear, linear, linearBand, remaining, opts.To, f),
+		project(Methods.Ewma, ewma, ewmaBand, remaining, opts.To, f),
+	} {
+		fc.Projections = append(fc.Projections, p)
+		fc.Breach = fc.Breach || p.Breach
+	}
+	return fc
+}
+
+func project(method string, rate float64, band float64, remaining float64, now time.Time, f *ForecastOptions) *Projection {
+	p := &Projection{
+		Method:       method,
+		BurnRate:     rate,
+		BurnRateLow:  math.Max(0, rate-band),
+		BurnRateHigh: rate + band,
+	}
+	p.ExhaustsAt = exhaustion(rate, remaining, now, f.Length)
+	p.Earliest = exhaustion(p.BurnRateHigh, remaining, now, f.Length)
+	p.Latest = exhaustion(p.BurnRateLow, remaining, now, f.Length)
+	p.Breach = p.ExhaustsAt != nil && p.ExhaustsAt.Before(f.End)
+	return p
+}
+
+// exhaustion is when the remaining share of the budget is spent at rate, a rate of 1
+// spending the whole budget over length. A spent budget is exhausted now.
+func exhaustion(rate float64, remaining float64, now time.Time, length time.Duration) *time.Time {
+	if remaining <= 0 {
+		return &now
+	}
+	if rate <= 0 {
+		return nil
+	}
+	hours := remaining * length.Hours() / rate
+	// Beyond a century is as good as never and would overflow a Duration
+	if hours > 100*365*24 {
+		return nil
+	}
+	t := now.Add(time.Duration(hours * float64(time.Hour)))
+	return &t
+}
+
+// stddev is the sample standard deviation, 0 for a single value
+func stddev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	squares := 0.0
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(squares / float64(len(values)-1))
+}
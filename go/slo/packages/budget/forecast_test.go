@@ -0,0 +1,176 @@
+package budget
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+)
+
+func TestExhaustion(t *testing.T) {
+	now := time.Unix(0, 0)
+	length := 10 * time.Hour
+	tests := []struct {
+		name      string
+		rate      float64
+		remaining float64
+		want      *time.Duration // After now, nil when never exhausted
+	}{
+		{name: "spent budget is exhausted now", rate: 1, remaining: 0, want: hours(0)},
+		{name: "overspent budget is exhausted now", rate: 0, remaining: -0.2, want: hours(0)},
+		{name: "no burn never exhausts", rate: 0, remaining: 0.5},
+		{name: "objective rate spends the rest of the window", rate: 1, remaining: 0.5, want: hours(5)},
+		{name: "double rate spends it twice as fast", rate: 2, remaining: 0.5, want: hours(2.5)},
+		{name: "beyond a century never exhausts", rate: 1e-9, remaining: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := exhaustion(tt.rate, tt.remaining, now, length)
+			switch {
+			case tt.want == nil && got != nil:
+				t.Errorf("exhaustion() = %v, want never", got)
+			case tt.want != nil && got == nil:
+				t.Errorf("exhaustion() = never, want %v", now.Add(*tt.want))
+			case tt.want != nil && !got.Equal(now.Add(*tt.want)):
+				t.Errorf("exhaustion() = %v, want %v", got, now.Add(*tt.want))
+			}
+		})
+	}
+}
+
+func hours(h float64) *time.Duration {
+	d := time.Duration(h * float64(time.Hour))
+	return &d
+}
+
+func TestForecast(t *testing.T) {
+	// Hourly burn rates of 0, 1 and 2 over the lookback, after a day that spent little budget
+	data := []Sample{
+		requests(at(20*time.Hour), 1000, 1000),
+		requests(at(150*time.Minute), 100, 100),
+		requests(at(90*time.Minute), 90, 100),
+		requests(at(30*time.Minute), 80, 100),
+	}
+	remaining := 1 - (30.0/1300)/0.1
+	length := 240 * time.Hour
+	tests := []struct {
+		name       string
+		end        time.Duration // After to
+		alpha      float64
+		linear     float64
+		ewma       float64
+		ewmaBand   float64
+		ewmaBreach bool
+	}{
+		{
+			name:     "ewma follows the latest hours",
+			end:      100 * time.Hour,
+			alpha:    0.5,
+			linear:   1,
+			ewma:     1.25,
+			ewmaBand: confidenceZ * math.Sqrt(0.6875),
+		},
+		{
+			name:       "ewma breaches before the window ends",
+			end:        160 * time.Hour,
+			alpha:      0.5,
+			linear:     1,
+			ewma:       1.25,
+			ewmaBand:   confidenceZ * math.Sqrt(0.6875),
+			ewmaBreach: true,
+		},
+		{
+			name:   "alpha of 1 only keeps the last hour",
+			end:    90 * time.Hour,
+			alpha:  1,
+			linear: 1,
+			ewma:   2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Compute(data, Options{
+				SliType:   models.Types.Availability,
+				Objective: 90,
+				From:      from,
+				To:        to,
+				Forecast: &ForecastOptions{
+					Lookback: 3 * time.Hour,
+					Alpha:    tt.alpha,
+					Length:   length,
+					End:      to.Add(tt.end),
+				},
+			})
+			if err != nil {
+				t.Fatalf("Compute() error = %v", err)
+			}
+			f := s.Forecast
+			if f.Buckets != 3 || len(f.Projections) != 2 {
+				t.Fatalf("Buckets, Projections = %d, %d, want 3, 2", f.Buckets, len(f.Projections))
+			}
+			linear, ewma := f.Projections[0], f.Projections[1]
+			if linear.Method != Methods.Linear || ewma.Method != Methods.Ewma {
+				t.Fatalf("methods = %s, %s", linear.Method, ewma.Method)
+			}
+			linearBand := confidenceZ * 1 / math.Sqrt(3)
+			if !approx(linear.BurnRate, tt.linear) || !approx(linear.BurnRateHigh, tt.linear+linearBand) {
+				t.Errorf("linear = %+v, want rate %g and band %g", *linear, tt.linear, linearBand)
+			}
+			if !approx(ewma.BurnRate, tt.ewma) || !approx(ewma.BurnRateHigh, tt.ewma+tt.ewmaBand) {
+				t.Errorf("ewma = %+v, want rate %g and band %g", *ewma, tt.ewma, tt.ewmaBand)
+			}
+			for _, p := range f.Projections {
+				want := to.Add(time.Duration(remaining * length.Hours() / p.BurnRate * float64(time.Hour)))
+				if p.ExhaustsAt == nil || p.ExhaustsAt.Sub(want).Abs() > time.Second {
+					t.Errorf("%s ExhaustsAt = %v, want %v", p.Method, p.ExhaustsAt, want)
+				}
+			}
+			if linear.Breach || ewma.Breach != tt.ewmaBreach || f.Breach != tt.ewmaBreach {
+				t.Errorf("Breach linear, ewma, forecast = %v, %v, %v, want false, %v, %v", linear.Breach, ewma.Breach, f.Breach, tt.ewmaBreach, tt.ewmaBreach)
+			}
+		})
+	}
+}
+
+func TestForecastWithoutRecentData(t *testing.T) {
+	s, err := Compute([]Sample{requests(at(20*time.Hour), 90, 100)}, Options{
+		SliType:   models.Types.Availability,
+		Objective: 90,
+		From:      from,
+		To:        to,
+		Forecast:  &ForecastOptions{Lookback: 3 * time.Hour, Alpha: 0.3, Length: 240 * time.Hour, End: to},
+	})
+	if err != nil {
+		t.Fatalf("Compute() error = %v", err)
+	}
+	if s.Forecast.Buckets != 0 || len(s.Forecast.Projections) != 0 || s.Forecast.Breach {
+		t.Errorf("Forecast = %+v, want no buckets or projections", *s.Forecast)
+	}
+}
+
+func TestForecastFractionalRates(t *testing.T) {
+	// Hourly burn rates of 0, 1 and 2 from request rates below one per second
+	data := []Sample{
+		requests(at(150*time.Minute), 0.1, 0.1),
+		requests(at(90*time.Minute), 0.09, 0.1),
+		requests(at(30*time.Minute), 0.08, 0.1),
+	}
+	s, err := Compute(data, Options{
+		SliType:   models.Types.Availability,
+		Objective: 90,
+		From:      from,
+		To:        to,
+		Forecast:  &ForecastOptions{Lookback: 3 * time.Hour, Alpha: 1, Length: 240 * time.Hour, End: to},
+	})
+	if err != nil {
+		t.Fatalf("Compute() error = %v", err)
+	}
+	f := s.Forecast
+	if f.Buckets != 3 || len(f.Projections) != 2 {
+		t.Fatalf("Buckets, Projections = %d, %d, want 3, 2", f.Buckets, len(f.Projections))
+	}
+	if linear, ewma := f.Projections[0], f.Projections[1]; !approx(linear.BurnRate, 1) || !approx(ewma.BurnRate, 2) {
+		t.Errorf("burn rates linear, ewma = %g, %g, want 1, 2", linear.BurnRate, ewma.BurnRate)
+	}
+}
@@ -1,18 +1,29 @@
 package clients
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
 	"time"
 
 	"github.com/blamelesshq/blameless-examples/slo/packages/config"
+	"github.com/blamelesshq/blameless-examples/slo/packages/logging"
 	"github.com/go-resty/resty/v2"
 )
 
 const apiPrefix = "/api/v1/services"
 const sloService = "SLOServiceCrud"
 const sloTimeseriesService = "SLOTimeSeriesServiceCrud"
+const requestIdHeader = "X-Request-Id"
 
 type BlamelessClient struct {
 	SloService           string
@@ -24,6 +35,29 @@ type Blameless interface {
 	Post(service string, method string, body json.RawMessage) (json.RawMessage, error)
 }
 
+var blamelessOncer sync.Once
+var bClient *resty.Client
+var bBreaker *CircuitBreaker
+var bErr error
+
+// blamelessHttpClient is shared so every call is held to the same rate limit. The auth
+// token is resolved on the first call, not when config is loaded.
+func blamelessHttpClient() (*resty.Client, error) {
+	blamelessOncer.Do(func() {
+		env := config.Environment().Blameless
+		token, err := env.AuthToken.Resolve()
+		if err != nil {
+			bErr = fmt.Errorf("unable to resolve blameless.authToken: %w", err)
+			return
+		}
+		bClient = newHttpClient(fmt.Sprintf("%s:%d", env.Host, env.Port), env.RateLimit)
+		bClient.SetAuthScheme("Bearer")
+		bClient.SetAuthToken(token)
+		bBreaker = NewCircuitBreaker("blameless", config.Environment().Http.CircuitBreaker)
+	})
+	return bClient, bErr
+}
+
 func NewBlamelessClient() *BlamelessClient {
 	return &BlamelessClient{
 		SloService:           sloService,
@@ -32,24 +66,95 @@ func NewBlamelessClient() *BlamelessClient {
 }
 
 func (c *BlamelessClient) Post(service string, method string, body json.RawMessage) (json.RawMessage, error) {
-	client := resty.New()
-	client.SetRetryCount(3).SetRetryWaitTime(5 * time.Second)
-	client.SetHostURL(fmt.Sprintf("%s:%d", config.Environment().Blameless.Host, config.Environment().Blameless.Port))
-	client.SetHeader("Accept", "application/json")
-
-	client.SetAuthScheme("Bearer")
-	client.SetAuthToken(config.Environment().Blameless.AuthToken)
-
+	client, err := blamelessHttpClient()
+	if err != nil {
+		return json.RawMessage{}, err
+	}
 	if c.SloService != service && c.SloTimeseriesService != service {
 		return json.RawMessage{}, fmt.Errorf("service name %s is not a valid identifier", service)
 	}
-	resp, err := client.R().
+	requestId := newRequestId()
+	logger := slog.With("requestId", requestId, "service", service, "method", method)
+	req := client.R().
 		SetHeader("Content-Type", "application/json").
-		SetBody(body).
-		Post(fmt.Sprintf("%s/%s/%s", apiPrefix, service, method))
+		SetHeader(requestIdHeader, requestId)
+	logger.Debug("blameless request", "headers", logging.Headers(req.Header), "body", string(body))
+	if config.Environment().Blameless.Gzip {
+		compressed, err := gzipBody(body)
+		if err != nil {
+			return json.RawMessage{}, fmt.Errorf("unable to compress request: %v", err)
+		}
+		req.SetHeader("Content-Encoding", "gzip").SetBody(compressed)
+	} else {
+		req.SetBody([]byte(body))
+	}
+
+	if err := bBreaker.Allow(); err != nil {
+		logger.Warn("blameless request rejected", "breaker", bBreaker.State().String())
+		return json.RawMessage{}, err
+	}
+	start := time.Now()
+	resp, err := req.Post(fmt.Sprintf("%s/%s/%s", apiPrefix, service, method))
+	latency := time.Since(start)
+	bBreaker.Record(err == nil && resp.StatusCode() < 500)
 	if err != nil {
-		return json.RawMessage{}, fmt.Errorf("unable to perform request \n%+v", err)
+		logger.Error("blameless request failed", "latency", latency, "error", err, "breaker", bBreaker.State().String())
+		return json.RawMessage{}, fmt.Errorf("unable to perform request \n%w", err)
+	}
+
+	level := slog.LevelInfo
+	if resp.IsError() {
+		level = slog.LevelWarn
+	}
+	logger.Log(context.Background(), level, "blameless response", "status", resp.StatusCode(), "latency", latency, "bytes", len(resp.Body()))
+	logger.Debug("blameless response body", "body", resp.String())
+	if resp.IsError() {
+		return json.RawMessage{}, &StatusError{Service: service, Method: method, StatusCode: resp.StatusCode(), Status: resp.Status()}
 	}
-	log.Println(resp.String())
 	return resp.Body(), nil
 }
+
+// StatusError is a request the Blameless API answered with an error status
+type StatusError struct {
+	Service    string
+	Method     string
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s/%s returned %s", e.Service, e.Method, e.Status)
+}
+
+// Retryable reports whether a failed request may succeed when sent again later: a
+// transport error, an open circuit breaker, 429 or a 5xx status. Other statuses, such
+// as validation rejections, fail the same way every time.
+func Retryable(err error) bool {
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.StatusCode == http.StatusTooManyRequests || status.StatusCode >= 500
+	}
+	var transport *url.Error
+	return errors.As(err, &transport) || errors.Is(err, ErrCircuitOpen)
+}
+
+func gzipBody(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// newRequestId returns a random identifier used to correlate a request across logs
+func newRequestId() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
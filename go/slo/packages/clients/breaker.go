@@ -0,0 +1,197 @@
+package clients
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/config"
+)
+
+// ErrCircuitOpen is returned without calling the backend while its breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// Breaker state is published through expvar under circuitBreakers.<backend>, served with --metrics-addr
+var breakerMetrics = expvar.NewMap("circuitBreakers")
+
+type BreakerState int
+
+const (
+	StateClosed BreakerState = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+type BreakerStats struct {
+	State    string `json:"state"`
+	Requests int    `json:"requests"`
+	Failures int    `json:"failures"`
+	Opened   int    `json:"opened"`
+	Rejected int    `json:"rejected"`
+}
+
+// CircuitBreaker stops calling a backend once the failure rate within a window
+// crosses the configured threshold. After the open timeout up to halfOpenRequests
+// probes are let through, it closes once all of them succeed and opens again on
+// any failure.
+type CircuitBreaker struct {
+	name string
+	cfg  config.CircuitBreaker
+	now  func() time.Time
+
+	mu          sync.Mutex
+	state       BreakerState
+	openedAt    time.Time
+	windowStart time.Time
+	requests    int
+	failures    int
+	probes      int // Probes let through since the breaker went half-open
+	successes   int // Probes that succeeded since the breaker went half-open
+	opened      int
+	rejected    int
+}
+
+func NewCircuitBreaker(name string, cfg config.CircuitBreaker) *CircuitBreaker {
+	b := &CircuitBreaker{
+		name: name,
+		cfg:  cfg,
+		now:  time.Now,
+	}
+	b.windowStart = b.now()
+	breakerMetrics.Set(name, expvar.Func(func() interface{} {
+		return b.Stats()
+	}))
+	return b
+}
+
+// Allow reports whether a call may proceed, every allowed call must be followed by Record
+func (b *CircuitBreaker) Allow() error {
+	if b.cfg.FailureRate <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout() {
+			b.rejected++
+			return fmt.Errorf("%s: %w", b.name, ErrCircuitOpen)
+		}
+		b.transition(StateHalfOpen)
+		fallthrough
+	case StateHalfOpen:
+		if b.probes >= b.halfOpenRequests() {
+			b.rejected++
+			return fmt.Errorf("%s: %w", b.name, ErrCircuitOpen)
+		}
+		b.probes++
+	}
+	return nil
+}
+
+// Record feeds the outcome of an allowed call back into the breaker
+func (b *CircuitBreaker) Record(success bool) {
+	if b.cfg.FailureRate <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateHalfOpen:
+		if !success {
+			b.transition(StateOpen)
+			return
+		}
+		b.successes++
+		if b.successes >= b.halfOpenRequests() {
+			b.transition(StateClosed)
+		}
+		return
+	case StateOpen:
+		// A call allowed before the breaker opened finished late, it has nothing new to say
+		return
+	}
+
+	if window := time.Duration(b.cfg.Window) * time.Second; window > 0 && b.now().Sub(b.windowStart) >= window {
+		b.resetWindow()
+	}
+	b.requests++
+	if !success {
+		b.failures++
+	}
+	if b.requests >= b.cfg.MinRequests && float64(b.failures)/float64(b.requests) >= b.cfg.FailureRate {
+		b.transition(StateOpen)
+	}
+}
+
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *CircuitBreaker) Stats() BreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return BreakerStats{
+		State:    b.state.String(),
+		Requests: b.requests,
+		Failures: b.failures,
+		Opened:   b.opened,
+		Rejected: b.rejected,
+	}
+}
+
+// transition must be called with the lock held
+func (b *CircuitBreaker) transition(to BreakerState) {
+	if b.state == to {
+		return
+	}
+	from := b.state
+	b.state = to
+	b.probes = 0
+	b.successes = 0
+	switch to {
+	case StateOpen:
+		b.opened++
+		b.openedAt = b.now()
+		slog.Warn("circuit breaker opened", "backend", b.name, "from", from.String(), "requests", b.requests, "failures", b.failures, "openTimeout", b.openTimeout())
+	case StateHalfOpen:
+		slog.Info("circuit breaker half-open", "backend", b.name, "probes", b.halfOpenRequests())
+	case StateClosed:
+		b.resetWindow()
+		slog.Info("circuit breaker closed", "backend", b.name)
+	}
+}
+
+func (b *CircuitBreaker) resetWindow() {
+	b.windowStart = b.now()
+	b.requests = 0
+	b.failures = 0
+}
+
+func (b *CircuitBreaker) openTimeout() time.Duration {
+	return time.Duration(b.cfg.OpenTimeout) * time.Second
+}
+
+func (b *CircuitBreaker) halfOpenRequests() int {
+	if b.cfg.HalfOpenRequests < 1 {
+		return 1
+	}
+	return b.cfg.HalfOpenRequests
+}
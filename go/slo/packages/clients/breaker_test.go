@@ -0,0 +1,126 @@
+package clients
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/config"
+)
+
+// testBreaker returns a breaker on a clock the test moves with advance
+func testBreaker(cfg config.CircuitBreaker) (*CircuitBreaker, func(time.Duration)) {
+	now := time.Unix(0, 0)
+	b := NewCircuitBreaker("test", cfg)
+	b.now = func() time.Time { return now }
+	b.windowStart = now
+	return b, func(d time.Duration) { now = now.Add(d) }
+}
+
+// call runs one call through the breaker, reporting whether it was allowed
+func call(b *CircuitBreaker, success bool) bool {
+	if err := b.Allow(); err != nil {
+		return false
+	}
+	b.Record(success)
+	return true
+}
+
+func TestCircuitBreakerOpens(t *testing.T) {
+	cfg := config.CircuitBreaker{FailureRate: 0.5, MinRequests: 4, Window: 60, OpenTimeout: 30, HalfOpenRequests: 1}
+	tests := []struct {
+		name     string
+		cfg      config.CircuitBreaker
+		outcomes []bool
+		want     BreakerState
+	}{
+		{name: "below min requests", cfg: cfg, outcomes: []bool{false, false, false}, want: StateClosed},
+		{name: "below failure rate", cfg: cfg, outcomes: []bool{true, true, false, true, true}, want: StateClosed},
+		{name: "at failure rate", cfg: cfg, outcomes: []bool{true, false, true, false}, want: StateOpen},
+		{name: "disabled", cfg: config.CircuitBreaker{}, outcomes: []bool{false, false, false, false, false}, want: StateClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := testBreaker(tt.cfg)
+			for _, ok := range tt.outcomes {
+				call(b, ok)
+			}
+			if got := b.State(); got != tt.want {
+				t.Errorf("State() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerWindowResets(t *testing.T) {
+	b, advance := testBreaker(config.CircuitBreaker{FailureRate: 0.5, MinRequests: 4, Window: 60, OpenTimeout: 30})
+	call(b, false)
+	call(b, false)
+	call(b, false)
+	advance(time.Minute)
+	call(b, false)
+	if got := b.State(); got != StateClosed {
+		t.Errorf("State() = %s, want failures of an expired window forgotten", got)
+	}
+}
+
+func TestCircuitBreakerFailsFastWhileOpen(t *testing.T) {
+	b, advance := testBreaker(config.CircuitBreaker{FailureRate: 0.5, MinRequests: 1, Window: 60, OpenTimeout: 30})
+	call(b, false)
+	err := b.Allow()
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Allow() = %v, want ErrCircuitOpen", err)
+	}
+	advance(29 * time.Second)
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Allow() = %v before the open timeout, want ErrCircuitOpen", err)
+	}
+	advance(time.Second)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() = %v after the open timeout, want a probe", err)
+	}
+	if got := b.State(); got != StateHalfOpen {
+		t.Errorf("State() = %s, want half-open", got)
+	}
+	if stats := b.Stats(); stats.Opened != 1 || stats.Rejected != 2 {
+		t.Errorf("Stats() = %+v, want opened once and 2 rejected", stats)
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	tests := []struct {
+		name     string
+		probes   int
+		outcomes []bool // Results of the probes let through, in order
+		want     BreakerState
+	}{
+		{name: "single probe closes", probes: 1, outcomes: []bool{true}, want: StateClosed},
+		{name: "single failed probe reopens", probes: 1, outcomes: []bool{false}, want: StateOpen},
+		{name: "one success of three stays half-open", probes: 3, outcomes: []bool{true}, want: StateHalfOpen},
+		{name: "three successes close", probes: 3, outcomes: []bool{true, true, true}, want: StateClosed},
+		{name: "late failure reopens", probes: 3, outcomes: []bool{true, true, false}, want: StateOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, advance := testBreaker(config.CircuitBreaker{FailureRate: 0.5, MinRequests: 1, Window: 60, OpenTimeout: 30, HalfOpenRequests: tt.probes})
+			call(b, false)
+			advance(30 * time.Second)
+
+			// Every probe is in flight before the first result comes back
+			for i := 0; i < tt.probes; i++ {
+				if err := b.Allow(); err != nil {
+					t.Fatalf("probe %d rejected: %v", i+1, err)
+				}
+			}
+			if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+				t.Fatalf("Allow() = %v beyond %d probes, want ErrCircuitOpen", err, tt.probes)
+			}
+			for _, ok := range tt.outcomes {
+				b.Record(ok)
+			}
+			if got := b.State(); got != tt.want {
+				t.Errorf("State() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package clients
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/config"
+	"github.com/go-resty/resty/v2"
+	"golang.org/x/time/rate"
+)
+
+// newHttpClient builds a resty client shared by every call to one backend. Requests
+// wait on the backend's token bucket, including retries, and failures are retried
+// with exponential backoff and jitter unless the server asks for a specific delay.
+func newHttpClient(hostURL string, limit config.RateLimit) *resty.Client {
+	env := config.Environment().Http
+	client := resty.New()
+	client.SetHostURL(hostURL)
+	client.SetHeader("Accept", "application/json")
+	if env.RequestTimeout > 0 {
+		client.SetTimeout(time.Duration(env.RequestTimeout) * time.Second)
+	}
+
+	limiter := newRateLimiter(limit)
+	client.OnBeforeRequest(func(c *resty.Client, r *resty.Request) error {
+		return limiter.Wait(r.Context())
+	})
+
+	client.SetRetryCount(env.Retry.Count).
+		SetRetryWaitTime(time.Duration(env.Retry.WaitTime) * time.Second).
+		SetRetryMaxWaitTime(time.Duration(env.Retry.MaxWaitTime) * time.Second).
+		SetRetryAfter(retryAfter).
+		AddRetryCondition(retryCondition(env.Retry))
+
+	return client
+}
+
+func newRateLimiter(limit config.RateLimit) *rate.Limiter {
+	if limit.RequestsPerSecond <= 0 {
+		return rate.NewLimiter(rate.Inf, 0)
+	}
+	burst := limit.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(limit.RequestsPerSecond), burst)
+}
+
+// retryCondition decides which failures are retried, a transport error or one of the configured status codes
+func retryCondition(cfg config.Retry) resty.RetryConditionFunc {
+	return func(resp *resty.Response, err error) bool {
+		if err != nil {
+			return cfg.TransportErrors
+		}
+		if resp == nil {
+			return false
+		}
+		for _, code := range cfg.StatusCodes {
+			if resp.StatusCode() == code {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// retryAfter honours the Retry-After header of 429 and 503 responses. Returning zero
+// falls back to resty's capped exponential backoff with jitter.
+func retryAfter(c *resty.Client, resp *resty.Response) (time.Duration, error) {
+	if resp == nil {
+		return 0, nil
+	}
+	if resp.StatusCode() != http.StatusTooManyRequests && resp.StatusCode() != http.StatusServiceUnavailable {
+		return 0, nil
+	}
+	return parseRetryAfter(resp.Header().Get("Retry-After"), time.Now()), nil
+}
+
+// parseRetryAfter reads either form of Retry-After, delay seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
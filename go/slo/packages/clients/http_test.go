@@ -0,0 +1,111 @@
+package clients
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/config"
+	"github.com/go-resty/resty/v2"
+)
+
+func response(status int, retryAfter string) *resty.Response {
+	raw := &http.Response{StatusCode: status, Header: http.Header{}}
+	if retryAfter != "" {
+		raw.Header.Set("Retry-After", retryAfter)
+	}
+	return &resty.Response{RawResponse: raw}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, time.May, 16, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{name: "missing", value: "", want: 0},
+		{name: "seconds", value: "120", want: 2 * time.Minute},
+		{name: "negative seconds", value: "-5", want: 0},
+		{name: "http date", value: now.Add(90 * time.Second).Format(http.TimeFormat), want: 90 * time.Second},
+		{name: "http date in the past", value: now.Add(-time.Minute).Format(http.TimeFormat), want: 0},
+		{name: "garbage", value: "soon", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value, now); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name string
+		resp *resty.Response
+		want time.Duration
+	}{
+		{name: "429 with a delay", resp: response(http.StatusTooManyRequests, "7"), want: 7 * time.Second},
+		{name: "503 with a delay", resp: response(http.StatusServiceUnavailable, "3"), want: 3 * time.Second},
+		{name: "502 ignores the header", resp: response(http.StatusBadGateway, "7"), want: 0},
+		{name: "no response", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := retryAfter(nil, tt.resp)
+			if err != nil || got != tt.want {
+				t.Errorf("retryAfter() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryCondition(t *testing.T) {
+	cfg := config.Retry{StatusCodes: []int{429, 503}, TransportErrors: true}
+	tests := []struct {
+		name string
+		cfg  config.Retry
+		resp *resty.Response
+		err  error
+		want bool
+	}{
+		{name: "configured status", cfg: cfg, resp: response(429, ""), want: true},
+		{name: "other status", cfg: cfg, resp: response(500, ""), want: false},
+		{name: "success", cfg: cfg, resp: response(200, ""), want: false},
+		{name: "transport error", cfg: cfg, err: errors.New("connection refused"), want: true},
+		{name: "transport errors disabled", cfg: config.Retry{StatusCodes: cfg.StatusCodes}, err: errors.New("connection refused"), want: false},
+		{name: "no response", cfg: cfg, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryCondition(tt.cfg)(tt.resp, tt.err); got != tt.want {
+				t.Errorf("retryCondition() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit config.RateLimit
+		burst int
+	}{
+		{name: "disabled", limit: config.RateLimit{}, burst: 0},
+		{name: "burst of at least one", limit: config.RateLimit{RequestsPerSecond: 5}, burst: 1},
+		{name: "configured burst", limit: config.RateLimit{RequestsPerSecond: 5, Burst: 10}, burst: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newRateLimiter(tt.limit)
+			if l.Burst() != tt.burst {
+				t.Errorf("Burst() = %d, want %d", l.Burst(), tt.burst)
+			}
+			if !l.Allow() {
+				t.Error("Allow() = false for the first request")
+			}
+		})
+	}
+}
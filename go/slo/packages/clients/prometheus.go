@@ -3,6 +3,7 @@ package clients
 import (
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"strconv"
 	"sync"
 	"time"
@@ -13,9 +14,14 @@ import (
 
 var prometheusOncer sync.Once
 var pClient *resty.Client
+var pBreaker *CircuitBreaker
+var pErr error
 
 type PrometheusClient struct {
-	client *resty.Client
+	client  *resty.Client
+	breaker *CircuitBreaker
+	step    time.Duration
+	err     error // Credentials that could not be resolved, returned by every query
 }
 
 type Prometheus interface {
@@ -29,34 +35,55 @@ type Values struct {
 }
 
 type QueryRangeResponse struct {
-	Status string
-	Data   struct {
+	Status    string
+	Error     string `json:"error"`
+	ErrorType string `json:"errorType"`
+	Data      struct {
 		Result []struct {
+			Metric map[string]string `json:"metric"`
 			Values []json.RawMessage `json:"values"`
 		}
 	}
 }
 
+// Series is one time series of a range query with its labels
+type Series struct {
+	Labels map[string]string
+	Values []Values
+}
+
 func formatTime(t time.Time) string {
 	return strconv.FormatFloat(float64(t.Unix())+float64(t.Nanosecond())/1e9, 'f', -1, 64)
 }
 
 func NewPrometheusClient() *PrometheusClient {
 	prometheusOncer.Do(func() {
-		pClient := resty.New()
-		pClient.SetRetryCount(3).SetRetryWaitTime(5 * time.Second)
-		pClient.SetHostURL(fmt.Sprintf("%s:%d", config.Environment().Prometheus.Host, config.Environment().Prometheus.Port))
-		pClient.SetHeader("Accept", "application/json")
-
-		// If you need to add auth
-		// // BASIC
-		// client.SetBasicAuth("user", "password")
-		// // Auth Token
-		// client.SetAuthToken("BC594900518B4F7EAC75BD37F019E08FBC594900518B4F7EAC75BD37F019E08F")
+		env := config.Environment().Prometheus
+		pClient = newHttpClient(fmt.Sprintf("%s:%d", env.Host, env.Port), env.RateLimit)
+		pBreaker = NewCircuitBreaker("prometheus", config.Environment().Http.CircuitBreaker)
+
+		// Credentials are resolved from env:, file: or exec: references in config
+		if env.BearerToken.IsSet() {
+			token, err := env.BearerToken.Resolve()
+			if err != nil {
+				pErr = fmt.Errorf("unable to resolve prometheus.bearerToken: %w", err)
+				return
+			}
+			pClient.SetAuthToken(token)
+		} else if env.Username != "" {
+			password, err := env.Password.Resolve()
+			if err != nil {
+				pErr = fmt.Errorf("unable to resolve prometheus.password: %w", err)
+				return
+			}
+			pClient.SetBasicAuth(env.Username, password)
+		}
 	})
 
 	return &PrometheusClient{
-		client: pClient,
+		client:  pClient,
+		breaker: pBreaker,
+		err:     pErr,
 	}
 }
 
@@ -70,38 +97,83 @@ func (v *Values) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// WithStep returns a client whose range queries use step instead of ingest.step
+func (p *PrometheusClient) WithStep(step time.Duration) *PrometheusClient {
+	c := *p
+	c.step = step
+	return &c
+}
+
+// Step is the resolution of range queries
+func (p *PrometheusClient) Step() time.Duration {
+	if p.step > 0 {
+		return p.step
+	}
+	return time.Duration(config.Environment().Ingest.Step) * time.Second
+}
+
+// QueryRange returns the samples of the first series a range query returns, SLI queries
+// are expected to aggregate to a single series
 func (p *PrometheusClient) QueryRange(query string, start time.Time, end time.Time) ([]Values, error) {
-	step := time.Duration(config.Environment().Ingest.Step) * time.Second
+	series, err := p.QueryRangeSeries(query, start, end)
+	if err != nil {
+		return []Values{}, err
+	}
+	// A query without samples in the range returns no series
+	if len(series) == 0 {
+		return []Values{}, nil
+	}
+	return series[0].Values, nil
+}
 
+// QueryRangeSeries returns every series a range query returns
+func (p *PrometheusClient) QueryRangeSeries(query string, start time.Time, end time.Time) ([]*Series, error) {
+	step := p.Step()
+
+	if p.err != nil {
+		return nil, p.err
+	}
+	if err := p.breaker.Allow(); err != nil {
+		slog.Warn("prometheus query rejected", "query", query, "breaker", p.breaker.State().String())
+		return nil, err
+	}
 	resp, err := p.client.R().SetQueryParams(map[string]string{
 		"query": query,
 		"start": formatTime(start),
 		"end":   formatTime(end),
 		"step":  step.String(),
 	}).Get("/api/v1/query_range")
+	p.breaker.Record(err == nil && resp.StatusCode() < 500)
 
 	if err != nil {
-		return []Values{}, fmt.Errorf("error querying Prometheus instance %s\nError: %v", fmt.Sprintf("%s:%d", config.Environment().Prometheus.Host, config.Environment().Prometheus.Port), err)
+		return nil, fmt.Errorf("error querying Prometheus instance %s\nError: %v", fmt.Sprintf("%s:%d", config.Environment().Prometheus.Host, config.Environment().Prometheus.Port), err)
 	}
 
+	var results *QueryRangeResponse
 	if resp.StatusCode() != 200 {
-		return []Values{}, fmt.Errorf("unsuccessful query")
+		// Prometheus explains rejected queries, such as PromQL syntax errors, in the body
+		if err := json.Unmarshal(resp.Body(), &results); err == nil && results.Error != "" {
+			return nil, fmt.Errorf("unsuccessful query, %s: %s", results.ErrorType, results.Error)
+		}
+		return nil, fmt.Errorf("unsuccessful query")
 	}
 
-	var results *QueryRangeResponse
 	if err := json.Unmarshal(resp.Body(), &results); err != nil {
-		return []Values{}, fmt.Errorf("unable to successfully unmarshall: \n%v", err)
+		return nil, fmt.Errorf("unable to successfully unmarshall: \n%v", err)
 	}
 
-	dataTuples := results.Data.Result[0].Values
-	tuples := make([]Values, len(dataTuples))
-	for i := 0; i < len(dataTuples); i++ {
-		var v Values
-		if err := json.Unmarshal(dataTuples[i], &v); err != nil {
-			return []Values{}, err
+	series := make([]*Series, len(results.Data.Result))
+	for i, result := range results.Data.Result {
+		tuples := make([]Values, len(result.Values))
+		for j := 0; j < len(result.Values); j++ {
+			var v Values
+			if err := json.Unmarshal(result.Values[j], &v); err != nil {
+				return nil, err
+			}
+			tuples[j] = v
 		}
-		tuples[i] = v
+		series[i] = &Series{Labels: result.Metric, Values: tuples}
 	}
 
-	return tuples, nil
+	return series, nil
 }
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/manifest"
+	"github.com/blamelesshq/blameless-examples/slo/packages/output"
+	"github.com/blamelesshq/blameless-examples/slo/packages/utils"
+	"github.com/spf13/cobra"
+)
+
+func apply() *cobra.Command {
+	var file string
+	var prune bool
+	apply := &cobra.Command{
+		Use:   "apply",
+		Short: "Apply an SLI and SLO manifest",
+		Long: `Create, update and with --prune delete SLIs and SLOs until Blameless matches a YAML or JSON manifest.
+SLIs are matched by name and SLOs by name within their SLI, applying the same manifest twice changes nothing.
+Pruning only deletes SLIs of services the manifest declares, and SLOs of the SLIs it declares.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			m, err := manifest.Load(file)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			applyManifest(cmd, m, manifestOrgId(cmd, m), prune, false)
+		},
+	}
+	addManifestFlags(apply, &file, &prune)
+	addYesFlag(apply)
+
+	return apply
+}
+
+// applyManifest plans the changes converging Blameless on m and makes them, asking
+// before any delete. With dryRun the plan is only printed, if the deletes are declined
+// nothing is changed and nil is returned.
+func applyManifest(cmd *cobra.Command, m *manifest.Manifest, orgId int, prune bool, dryRun bool) *manifest.Plan {
+	plan, err := manifest.NewPlan(m, orgId, prune)
+	if err != nil {
+		log.Fatalf("unable to plan changes: \n%+v", err)
+	}
+	if dryRun {
+		render(cmd, plan.Changes, changeTable(plan.Changes))
+		return plan
+	}
+	if deletes := plan.Count(manifest.Delete); deletes > 0 {
+		if !utils.ConfirmInput(cmd, "yes", fmt.Sprintf("Delete %d SLI(s) and SLO(s)", deletes)) {
+			aborted()
+			return nil
+		}
+	}
+	err = plan.Apply()
+	render(cmd, plan.Changes, changeTable(plan.Changes))
+	if err != nil {
+		log.Fatalf("apply stopped: \n%+v", err)
+	}
+	return plan
+}
+
+func addManifestFlags(c *cobra.Command, file *string, prune *bool) {
+	addOrgIdFlag(c)
+	c.Flags().StringVarP(file, "filename", "f", "", "YAML or JSON manifest, - reads stdin")
+	c.Flags().BoolVar(prune, "prune", false, "delete SLIs and SLOs of the manifest's services that it does not declare")
+	c.MarkFlagRequired("filename")
+}
+
+// manifestOrgId prefers --org-id, then the manifest's orgId, then blameless.orgId from config
+func manifestOrgId(cmd *cobra.Command, m *manifest.Manifest) int {
+	if !cmd.Flags().Changed("org-id") && m.OrgId != 0 {
+		return m.OrgId
+	}
+	return utils.IntInput(cmd, "org-id", "Org ID")
+}
+
+func changeTable(changes []*manifest.Change) *output.Table {
+	t := output.NewTable("Action", "Kind", "SLI", "Name", "ID")
+	for _, c := range changes {
+		t.AddRow(c.Action, c.Kind, c.Sli, c.Name, c.Id)
+	}
+	return t
+}
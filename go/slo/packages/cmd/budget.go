@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/budget"
+	"github.com/blamelesshq/blameless-examples/slo/packages/clients"
+	"github.com/blamelesshq/blameless-examples/slo/packages/config"
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+	"github.com/blamelesshq/blameless-examples/slo/packages/output"
+	"github.com/blamelesshq/blameless-examples/slo/packages/utils"
+	"github.com/spf13/cobra"
+)
+
+func budgetCmd() *cobra.Command {
+	b := &cobra.Command{
+		Use:   "budget",
+		Short: "Error budget domain primary command",
+		Long:  `Compute error budgets of SLOs from the raw data in Prometheus`,
+	}
+
+	b.AddCommand(budgetStatus())
+
+	return b
+}
+
+// sloStatus is the error budget of one SLO
+type sloStatus struct {
+	SliId  int    `json:"sliId"`
+	Sli    string `json:"sli"`
+	SloId  int    `json:"sloId"`
+	Slo    string `json:"slo"`
+	Window string `json:"window"`
+	*budget.Status
+}
+
+func budgetStatus() *cobra.Command {
+	var sliIds []int
+	var all bool
+	var burnWindows []string
+	var forecast bool
+	var lookback string
+	var alpha float64
+	status := &cobra.Command{
+		Use:   "status",
+		Short: "Show achieved SLI, remaining error budget and burn rates",
+		Long: `Query the SLI metric paths in Prometheus over each SLO window and measure them against the SLO objective.
+Availability SLIs count good and valid requests, latency SLIs count the samples at or under the SLO threshold as good.
+A burn rate of 1 spends the error budget exactly over the SLO window, higher rates exhaust it early.
+With --forecast the hourly burn rates over the lookback project when the remaining budget runs out, as a linear
+rate over the whole lookback and as an exponentially weighted rate favoring recent hours, each with 95% confidence bands.
+SLOs projected to run out before their window ends, or within one window for rolling windows, are flagged as breaching.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			orgId := utils.IntInput(cmd, "org-id", "Org ID")
+			windows, err := budget.ParseWindows(burnWindows)
+			if err != nil {
+				log.Fatalf("unable to parse burn windows: \n%+v", err)
+			}
+			lookbackWindow, err := budget.ParseWindow(lookback)
+			if err != nil {
+				log.Fatalf("unable to parse forecast lookback: \n%+v", err)
+			}
+			if alpha <= 0 || alpha > 1 {
+				log.Fatalf("forecast alpha must be above 0 and at most 1, got %g", alpha)
+			}
+			sliIds = selectedSliIds(orgId, "sli-id", sliIds, all, "report on")
+
+			p := clients.NewPrometheusClient()
+			now := time.Now()
+			statuses := []*sloStatus{}
+			for _, id := range sliIds {
+				resp, err := models.GetSli(&models.GetSliRequest{OrgId: orgId, Id: id})
+				if err != nil {
+					log.Fatalf("unable to fetch SLI %d: \n%+v", id, err)
+				}
+				sli := resp.Sli
+				st, err := sli.GetSliType()
+				if err != nil {
+					log.Fatalf("unable to get SLI type: \n%+v", err)
+				}
+				slos, err := models.ListAllSlos(&models.ListSlosRequest{OrgId: orgId, SliId: id})
+				if err != nil {
+					log.Fatalf("unable to list SLOs of SLI %d: \n%+v", id, err)
+				}
+				if len(slos) == 0 {
+					slog.Warn("skipping sli", "sliId", id, "reason", "no SLOs")
+					continue
+				}
+				if st.SliType.Name != models.Types.Availability && st.SliType.Name != models.Types.Latency {
+					slog.Warn("skipping sli", "sliId", id, "reason", fmt.Sprintf("error budgets are computed for availability and latency SLIs, not %s", st.SliType.Name))
+					continue
+				}
+
+				// Query once over the longest SLO window, each SLO only counts its own range
+				from := now
+				for _, slo := range slos {
+					start, _, err := budget.SloWindow(slo.Window, now)
+					if err != nil {
+						log.Fatalf("unable to read window of SLO %d: \n%+v", slo.Id, err)
+					}
+					if start.Before(from) {
+						from = start
+					}
+				}
+				data, err := budget.Fetch(p, sli, st.SliType.Name, from, now)
+				if err != nil {
+					log.Fatalf("unable to query SLI %d from prometheus: \n%+v", id, err)
+				}
+
+				for _, slo := range slos {
+					start, end, _ := budget.SloWindow(slo.Window, now)
+					opts := budget.Options{
+						SliType:     st.SliType.Name,
+						Objective:   slo.ObjectivePercentage,
+						Threshold:   slo.Threshold,
+						From:        start,
+						To:          end,
+						BurnWindows: windows,
+					}
+					if forecast {
+						length := budget.SloWindowLength(slo.Window, now)
+						windowEnd := budget.SloWindowEnd(slo.Window, now)
+						if slo.Window.Type == models.WindowTypes.Rolling {
+							windowEnd = now.Add(length)
+						}
+						opts.Forecast = &budget.ForecastOptions{
+							Lookback: lookbackWindow.Duration,
+							Alpha:    alpha,
+							Length:   length,
+							End:      windowEnd,
+						}
+					}
+					s, err := budget.Compute(data, opts)
+					if err != nil {
+						slog.Warn("skipping slo", "sloId", slo.Id, "reason", err)
+						continue
+					}
+					statuses = append(statuses, &sloStatus{
+						SliId:  sli.Id,
+						Sli:    sli.Name,
+						SloId:  slo.Id,
+						Slo:    slo.Name,
+						Window: slo.Window.String(),
+						Status: s,
+					})
+				}
+			}
+			if forecast {
+				render(cmd, statuses, forecastTable(statuses))
+				return
+			}
+			render(cmd, statuses, statusTable(statuses, windows))
+		},
+	}
+	addOrgIdFlag(status)
+	addSliSelectionFlags(status, "sli-id", &sliIds, &all, "report on")
+	status.Flags().StringSliceVar(&burnWindows, "burn-window", []string{}, "window to report the burn rate over such as 1h or 3d, repeatable, defaults to budget.burnWindows")
+	defaultFromConfig(status, "burn-window", func(env *config.Config) string { return strings.Join(env.Budget.BurnWindows, ",") })
+	status.Flags().BoolVar(&forecast, "forecast", false, "project when the error budget runs out from recent burn rates")
+	status.Flags().StringVar(&lookback, "lookback", "", "range of recent burn rates forecasts project from, defaults to budget.forecastLookback")
+	defaultFromConfig(status, "lookback", func(env *config.Config) string { return env.Budget.ForecastLookback })
+	status.Flags().Float64Var(&alpha, "alpha", 0, "weight of the latest hour in the exponentially weighted forecast, defaults to budget.forecastAlpha")
+	defaultFromConfig(status, "alpha", func(env *config.Config) string { return strconv.FormatFloat(env.Budget.ForecastAlpha, 'g', -1, 64) })
+
+	return status
+}
+
+func statusTable(statuses []*sloStatus, windows []budget.Window) *output.Table {
+	headers := []string{"SLI", "SLO", "Objective %", "Window", "Valid", "Achieved %", "Budget Remaining %"}
+	for _, w := range windows {
+		headers = append(headers, fmt.Sprintf("Burn %s", w.Name))
+	}
+	t := output.NewTable(headers...)
+	for _, s := range statuses {
+		row := []interface{}{s.Sli, s.Slo, formatPercent(s.Objective), s.Window, formatCount(s.Valid), "-", "-"}
+		if s.Valid > 0 {
+			row[5] = formatPercent(s.Achieved)
+			row[6] = formatPercent(s.BudgetRemaining)
+		}
+		for _, b := range s.BurnRates {
+			rate := "-"
+			if b.Valid > 0 {
+				rate = strconv.FormatFloat(b.Rate, 'f', 2, 64)
+			}
+			row = append(row, rate)
+		}
+		t.AddRow(row...)
+	}
+	return t
+}
+
+// forecastTable lists one row per forecast method of each SLO
+func forecastTable(statuses []*sloStatus) *output.Table {
+	t := output.NewTable("SLI", "SLO", "Budget Remaining %", "Method", "Burn Rate", "Exhausts At", "Earliest", "Latest", "Breach")
+	for _, s := range statuses {
+		remaining := "-"
+		if s.Valid > 0 {
+			remaining = formatPercent(s.BudgetRemaining)
+		}
+		if len(s.Forecast.Projections) == 0 {
+			t.AddRow(s.Sli, s.Slo, remaining, "-", "-", "no data", "-", "-", false)
+			continue
+		}
+		for _, p := range s.Forecast.Projections {
+			rate := fmt.Sprintf("%.2f (%.2f-%.2f)", p.BurnRate, p.BurnRateLow, p.BurnRateHigh)
+			t.AddRow(s.Sli, s.Slo, remaining, p.Method, rate, formatExhaustion(p.ExhaustsAt), formatExhaustion(p.Earliest), formatExhaustion(p.Latest), p.Breach)
+		}
+	}
+	return t
+}
+
+func formatExhaustion(t *time.Time) string {
+	if t == nil {
+		return "never"
+	}
+	return t.UTC().Format("2006-01-02 15:04")
+}
+
+func formatPercent(f float64) string {
+	return strconv.FormatFloat(f, 'f', 3, 64)
+}
+
+// formatCount shows an event count, which is fractional for counts summed from rates
+func formatCount(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
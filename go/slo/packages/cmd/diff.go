@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/manifest"
+	"github.com/blamelesshq/blameless-examples/slo/packages/output"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+// driftExitCode is returned by diff when Blameless does not match the manifest, errors exit with 1
+const driftExitCode = 2
+
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+)
+
+var actionSymbols = map[manifest.Action]string{
+	manifest.Create: "+",
+	manifest.Update: "~",
+	manifest.Delete: "-",
+}
+
+var actionColors = map[manifest.Action]string{
+	manifest.Create: colorGreen,
+	manifest.Update: colorYellow,
+	manifest.Delete: colorRed,
+}
+
+func diff() *cobra.Command {
+	var file string
+	var prune bool
+	var noColor bool
+	diff := &cobra.Command{
+		Use:     "diff",
+		Aliases: []string{"plan"},
+		Short:   "Preview the changes apply would make",
+		Long: `Compare a manifest with the SLIs and SLOs in Blameless and print what apply would create, update or delete.
+Exits with 2 when Blameless differs from the manifest so CI can alert on drift, and 1 on errors.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			m, err := manifest.Load(file)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			plan, err := manifest.NewPlan(m, manifestOrgId(cmd, m), prune)
+			if err != nil {
+				log.Fatalf("unable to plan changes: \n%+v", err)
+			}
+
+			format, _ := cmd.Flags().GetString("output")
+			if format == output.Formats.Table {
+				color := !noColor && os.Getenv("NO_COLOR") == "" && isatty.IsTerminal(os.Stdout.Fd())
+				writeDiff(os.Stdout, plan, color)
+			} else {
+				render(cmd, plan, changeTable(plan.Changes))
+			}
+			if plan.HasChanges() {
+				os.Exit(driftExitCode)
+			}
+		},
+	}
+	addManifestFlags(diff, &file, &prune)
+	diff.Flags().BoolVar(&noColor, "no-color", false, "print the diff without colors, also set by NO_COLOR")
+
+	return diff
+}
+
+// writeDiff prints every change with the fields it touches, unchanged entries are left out
+func writeDiff(w io.Writer, plan *manifest.Plan, color bool) {
+	paint := func(action manifest.Action, s string) string {
+		if !color {
+			return s
+		}
+		return actionColors[action] + s + colorReset
+	}
+
+	if !plan.HasChanges() {
+		fmt.Fprintln(w, "No changes, Blameless matches the manifest.")
+		return
+	}
+	for _, c := range plan.Changes {
+		if c.Action == manifest.Unchanged {
+			continue
+		}
+		title := fmt.Sprintf("%s %s %q", actionSymbols[c.Action], c.Kind, c.Name)
+		if c.Sli != "" {
+			title += fmt.Sprintf(" on sli %q", c.Sli)
+		}
+		if c.Id != 0 {
+			title += fmt.Sprintf(" (id %d)", c.Id)
+		}
+		fmt.Fprintln(w, paint(c.Action, title))
+		for _, f := range c.Fields {
+			switch c.Action {
+			case manifest.Create:
+				fmt.Fprintln(w, paint(c.Action, fmt.Sprintf("    + %s: %s", f.Field, f.Desired)))
+			case manifest.Delete:
+				fmt.Fprintln(w, paint(c.Action, fmt.Sprintf("    - %s: %s", f.Field, f.Current)))
+			default:
+				fmt.Fprintf(w, "    %s:\n", f.Field)
+				fmt.Fprintln(w, paint(manifest.Delete, fmt.Sprintf("      - %s", f.Current)))
+				fmt.Fprintln(w, paint(manifest.Create, fmt.Sprintf("      + %s", f.Desired)))
+			}
+		}
+	}
+	fmt.Fprintf(w, "\nPlan: %d to create, %d to update, %d to delete.\n",
+		plan.Count(manifest.Create), plan.Count(manifest.Update), plan.Count(manifest.Delete))
+}
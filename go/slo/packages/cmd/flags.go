@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/config"
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+	"github.com/blamelesshq/blameless-examples/slo/packages/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// configDefaults holds the flags that default to a config value, see defaultFromConfig
+var configDefaults = map[*pflag.Flag]func(*config.Config) string{}
+
+// defaultFromConfig defaults flag name of c to a config value. Config is only loaded once a
+// command runs, so building the command tree and --help work without a config or secrets.
+func defaultFromConfig(c *cobra.Command, name string, value func(*config.Config) string) {
+	configDefaults[c.Flags().Lookup(name)] = value
+}
+
+// applyConfigDefaults sets the flags of cmd left unset on the command line to their config default
+func applyConfigDefaults(cmd *cobra.Command) {
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		value, ok := configDefaults[f]
+		if !ok || f.Changed {
+			return
+		}
+		if err := f.Value.Set(value(config.Environment())); err != nil {
+			log.Fatalf("invalid config default of --%s: \n%+v", f.Name, err)
+		}
+	})
+}
+
+// addOrgIdFlag registers --org-id, defaulting to blameless.orgId from config
+func addOrgIdFlag(c *cobra.Command) {
+	c.Flags().Int("org-id", 0, "Blameless organization ID (default from blameless.orgId)")
+	defaultFromConfig(c, "org-id", func(env *config.Config) string { return strconv.Itoa(env.Blameless.OrgId) })
+}
+
+// addQueryFlags registers the metric path queries, --query for single query SLI types
+// and --good-query with --valid-query for availability
+func addQueryFlags(c *cobra.Command) {
+	c.Flags().String("query", "", "PromQL query of a latency, throughput, saturation, durability or correctness SLI")
+	c.Flags().String("good-query", "", "PromQL query counting good requests of an availability SLI")
+	c.Flags().String("valid-query", "", "PromQL query counting valid requests of an availability SLI")
+}
+
+func addYesFlag(c *cobra.Command) {
+	c.Flags().BoolP("yes", "y", false, "skip the confirmation prompt")
+}
+
+// addSliSelectionFlags registers flag, --sli-id on most commands, and --all, read back with selectedSliIds
+func addSliSelectionFlags(c *cobra.Command, flag string, sliIds *[]int, all *bool, purpose string) {
+	c.Flags().IntSliceVar(sliIds, flag, []int{}, fmt.Sprintf("SLI to %s, repeatable", purpose))
+	c.Flags().BoolVar(all, "all", false, fmt.Sprintf("%s every SLI of the org", purpose))
+}
+
+// selectedSliIds returns the SLIs chosen with flag, or every SLI of the org with --all
+func selectedSliIds(orgId int, flag string, sliIds []int, all bool, purpose string) []int {
+	if all {
+		slis, err := models.ListAllSlis(&models.ListSlisRequest{OrgId: orgId})
+		if err != nil {
+			log.Fatalf("unable to list SLIs: \n%+v", err)
+		}
+		sliIds = []int{}
+		for _, s := range slis {
+			sliIds = append(sliIds, s.Id)
+		}
+	}
+	if len(sliIds) == 0 {
+		log.Fatalf("provide SLIs to %s with --%s or --all", purpose, flag)
+	}
+	return sliIds
+}
+
+// parseTime reads an RFC 3339 time, a date, now, or a duration before now such as 90m or 7d
+func parseTime(value string, now time.Time) (time.Time, error) {
+	if value == "now" {
+		return now, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	d, err := utils.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, use RFC 3339, YYYY-MM-DD, now or a duration before now such as 6h or 7d", value)
+	}
+	return now.Add(-d), nil
+}
+
+// timeRange parses --from and --to, rejecting empty ranges
+func timeRange(from string, to string) (time.Time, time.Time) {
+	now := time.Now()
+	start, err := parseTime(from, now)
+	if err != nil {
+		log.Fatalf("unable to parse --from: \n%+v", err)
+	}
+	end, err := parseTime(to, now)
+	if err != nil {
+		log.Fatalf("unable to parse --to: \n%+v", err)
+	}
+	if !start.Before(end) {
+		log.Fatalf("--from %s must be before --to %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+	return start, end
+}
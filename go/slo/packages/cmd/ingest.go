@@ -1,32 +1,54 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
 
+	"github.com/blamelesshq/blameless-examples/slo/packages/clients"
+	"github.com/blamelesshq/blameless-examples/slo/packages/config"
+	"github.com/blamelesshq/blameless-examples/slo/packages/ingest"
 	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+	"github.com/blamelesshq/blameless-examples/slo/packages/outbox"
+	"github.com/blamelesshq/blameless-examples/slo/packages/output"
+	"github.com/blamelesshq/blameless-examples/slo/packages/reconcile"
 	"github.com/blamelesshq/blameless-examples/slo/packages/utils"
 	"github.com/spf13/cobra"
 )
 
-func ingest() *cobra.Command {
+func ingestCmd() *cobra.Command {
 	ingest := &cobra.Command{
 		Use:   "ingest",
 		Short: "Ingest domain primary command",
 		Long:  `SLI ingest commands begin here. `,
 	}
 
+	ingest.AddCommand(ingestSli())
+	ingest.AddCommand(ingestGaps())
+	ingest.AddCommand(ingestBackfill())
+
 	return ingest
 }
 
 func ingestSli() *cobra.Command {
-	ingest := &cobra.Command{
-		Use:   "ingest",
+	var dryRun bool
+	var dryRunFile string
+	run := &cobra.Command{
+		Use:   "run",
 		Short: "Ingest scheduling for an SLI",
-		Long:  `Ingest an SLIs backfill or regular ingest period`,
+		Long: `Ingest an SLIs regular ingest period, the last ingest.period, from the queries on its metric path.
+Use sli ingest backfill for earlier ranges.
+With --dry-run the queries run but nothing is posted, the planned window is listed with its point count,
+min/max/avg values and samples without a value, followed by a sample of the payload. --dry-run-file also writes the full payload.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			orgId := utils.IntPrompt("Org ID")
-			sliId := utils.IntPrompt("SLI ID")
-			// backfill := utils.BooleanPrompt("Backfill ?")
+			orgId := utils.IntInput(cmd, "org-id", "Org ID")
+			sliId := utils.IntInput(cmd, "sli-id", "SLI ID")
 
 			resp, err := models.GetSli(&models.GetSliRequest{
 				OrgId: orgId,
@@ -37,11 +59,381 @@ func ingestSli() *cobra.Command {
 				log.Fatalf("unable to fetch SLI: %+v", err)
 			}
 
-			_, err = resp.Sli.GetSliType()
-			if err != nil {
-				log.Fatalf("unable to get SLI type: %+v", err)
+			p := clients.NewPrometheusClient()
+			if dryRun || dryRunFile != "" {
+				preview, err := ingest.PreviewRegular(p, resp.Sli)
+				if err != nil {
+					log.Fatalf("unable to preview ingest: \n%+v", err)
+				}
+				renderPreview(cmd, preview, dryRunFile)
+				return
 			}
+
+			results, err := ingest.RegularSli(p, resp.Sli)
+			// Queued batches are replayed from the outbox, the ingest itself went through
+			if err != nil && !errors.Is(err, outbox.ErrQueued) {
+				log.Fatalf("unable to ingest SLI: \n%+v", err)
+			}
+			posted, queued := 0, 0
+			if results.SliRawData != nil {
+				posted = len(*results.SliRawData)
+			}
+			for _, failed := range results.Errors {
+				queued += len(failed.Request.RawData)
+			}
+			t := output.NewTable("Posted", "Queued")
+			t.AddRow(posted, queued)
+			render(cmd, map[string]int{"posted": posted, "queued": queued}, t)
 		},
 	}
-	return ingest
+	addOrgIdFlag(run)
+	run.Flags().Int("sli-id", 0, "SLI to ingest")
+	run.Flags().BoolVar(&dryRun, "dry-run", false, "run the queries and show what would be posted without posting")
+	run.Flags().StringVar(&dryRunFile, "dry-run-file", "", "write the full payload of a dry run to this file, implies --dry-run")
+	return run
+}
+
+// renderPreview lists the windows of a dry run, in table output followed by a sample of the payload
+func renderPreview(cmd *cobra.Command, preview *ingest.Preview, file string) {
+	if file != "" {
+		f, err := os.Create(file)
+		if err != nil {
+			log.Fatalf("unable to create %s: \n%+v", file, err)
+		}
+		if err := preview.WritePayload(f); err != nil {
+			f.Close()
+			log.Fatalf("unable to write %s: \n%+v", file, err)
+		}
+		if err := f.Close(); err != nil {
+			log.Fatalf("unable to write %s: \n%+v", file, err)
+		}
+		slog.Info("dry run payload written", "file", file, "points", preview.Points)
+	}
+
+	headers := []string{"From", "To", "Points", "NaN"}
+	for _, f := range preview.Fields {
+		headers = append(headers, fmt.Sprintf("%s min/max/avg", f.Field))
+	}
+	t := output.NewTable(headers...)
+	for _, w := range preview.Windows {
+		row := []interface{}{w.From.UTC().Format("2006-01-02 15:04:05"), w.To.UTC().Format("2006-01-02 15:04:05"), w.Points, w.NaN}
+		row = append(row, fieldStats(w.Fields)...)
+		t.AddRow(row...)
+	}
+	total := []interface{}{"total", "", preview.Points, preview.NaN}
+	t.AddRow(append(total, fieldStats(preview.Fields)...)...)
+	render(cmd, preview, t)
+
+	if format, _ := cmd.Flags().GetString("output"); format != output.Formats.Table {
+		return
+	}
+	sample, err := json.MarshalIndent(preview.Sample, "", "  ")
+	if err != nil {
+		log.Fatalf("unable to print sample payload: \n%+v", err)
+	}
+	fmt.Printf("\nSample payload (%d of %d points):\n%s\n", len(preview.Sample), preview.Points, sample)
+}
+
+func fieldStats(stats []*ingest.FieldStats) []interface{} {
+	values := []interface{}{}
+	for _, f := range stats {
+		values = append(values, fmt.Sprintf("%d/%d/%.1f", f.Min, f.Max, f.Avg))
+	}
+	return values
+}
+
+// sliGap is a run of step windows an SLI has no raw data for
+type sliGap struct {
+	SliId int    `json:"sliId"`
+	Sli   string `json:"sli"`
+	reconcile.Window
+	Filled int `json:"filled"`
+}
+
+// gapQueryDistance is how close gaps are to be filled with a single Prometheus query
+const gapQueryDistance = time.Hour
+
+func ingestGaps() *cobra.Command {
+	var sliIds []int
+	var all bool
+	var lookback int
+	var dryRun bool
+	gaps := &cobra.Command{
+		Use:   "gaps",
+		Short: "Find and fill holes in the raw data of SLIs",
+		Long: `Read back the raw data posted for each SLI and list the ingest.step windows over the lookback that have none.
+The latest ingest.period is left out as regular ingest has yet to post it.
+The gaps are then re-ingested from Prometheus through the SLI metric path, nearby gaps sharing one query.
+With --dry-run the gaps are only listed.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			orgId := utils.IntInput(cmd, "org-id", "Org ID")
+			if lookback < 1 {
+				log.Fatalf("lookback must be at least 1 day, got %d", lookback)
+			}
+			sliIds = selectedSliIds(orgId, "sli-id", sliIds, all, "scan")
+
+			env := config.Environment().Ingest
+			step := time.Duration(env.Step) * time.Second
+			now := time.Now()
+			from := now.AddDate(0, 0, -lookback).Truncate(step)
+			to := now.Add(-time.Duration(env.Period) * time.Second).Truncate(step)
+
+			p := clients.NewPrometheusClient()
+			found := []*sliGap{}
+			for _, id := range sliIds {
+				resp, err := models.GetSli(&models.GetSliRequest{OrgId: orgId, Id: id})
+				if err != nil {
+					log.Fatalf("unable to fetch SLI %d: \n%+v", id, err)
+				}
+				stored, err := models.GetAllRawData(&models.GetManyRequest{OrgId: orgId, SliId: id, Start: int(from.Unix()), End: int(to.Unix())})
+				if err != nil {
+					log.Fatalf("unable to read raw data of SLI %d: \n%+v", id, err)
+				}
+				windows := reconcile.Gaps(stored, from, to, step)
+				filled := map[int]int{}
+				if !dryRun && len(windows) > 0 {
+					st, err := resp.Sli.GetSliType()
+					if err != nil {
+						log.Fatalf("unable to get SLI type: \n%+v", err)
+					}
+					for _, q := range reconcile.Coalesce(windows, gapQueryDistance) {
+						data, err := ingest.Fetch(p, resp.Sli, time.Unix(int64(q.Start), 0), time.Unix(int64(q.End), 0))
+						if err != nil {
+							log.Fatalf("unable to query SLI %d from prometheus: \n%+v", id, err)
+						}
+						// The query also covers the data between coalesced gaps, which is already stored
+						data = reconcile.Within(data, windows)
+						if len(data) == 0 {
+							continue
+						}
+						if _, err := ingest.Post(st.SliType.Name, data); err != nil && !errors.Is(err, outbox.ErrQueued) {
+							log.Fatalf("unable to post raw data of SLI %d: \n%+v", id, err)
+						}
+						for _, g := range windows {
+							filled[g.Start] += len(reconcile.Within(data, []reconcile.Window{g}))
+						}
+					}
+				}
+				for _, g := range windows {
+					found = append(found, &sliGap{SliId: id, Sli: resp.Sli.Name, Window: g, Filled: filled[g.Start]})
+				}
+			}
+
+			headers := []string{"SLI", "Start", "End", "Steps", "Filled"}
+			if dryRun {
+				headers = headers[:4]
+			}
+			t := output.NewTable(headers...)
+			for _, g := range found {
+				row := []interface{}{g.Sli, formatUnix(g.Start), formatUnix(g.End), g.Points, g.Filled}
+				t.AddRow(row[:len(headers)]...)
+			}
+			render(cmd, found, t)
+		},
+	}
+	addOrgIdFlag(gaps)
+	addSliSelectionFlags(gaps, "sli-id", &sliIds, &all, "scan")
+	gaps.Flags().IntVar(&lookback, "lookback", 0, "days to scan back from now, defaults to ingest.gapLookback")
+	defaultFromConfig(gaps, "lookback", func(env *config.Config) string { return strconv.Itoa(env.Ingest.GapLookback) })
+	gaps.Flags().BoolVar(&dryRun, "dry-run", false, "list the gaps without re-ingesting them")
+
+	return gaps
+}
+
+// sliBackfill is the summary of a range backfill for one SLI
+type sliBackfill struct {
+	SliId   int    `json:"sliId"`
+	Sli     string `json:"sli"`
+	Windows int    `json:"windows"`
+	Done    int    `json:"done"`
+	Points  int    `json:"points"`
+	Error   string `json:"error,omitempty"`
+}
+
+func ingestBackfill() *cobra.Command {
+	var sliIds []int
+	var all bool
+	var from string
+	var to string
+	var step time.Duration
+	var concurrency int
+	var statePath string
+	var resume string
+	var dryRun bool
+	var dryRunFile string
+	backfill := &cobra.Command{
+		Use:   "backfill",
+		Short: "Backfill the raw data of SLIs over a time range",
+		Long: `Query the metric path of each SLI over [--from, --to) an hour of steps at a time and post the raw data,
+--concurrency windows running at once. --from and --to take RFC 3339 times, dates, now or a duration before now such as 7d,
+--from defaults to ingest.backfill days before now.
+Progress is shown on stderr and a summary per SLI is printed at the end.
+A run that fails or is interrupted writes its state to --state, by default backfill-<time>.json,
+and --resume with that file backfills only the windows that were not posted.
+Batches that fail to post are queued in the outbox, which is replayed every outbox.flushInterval while the backfill runs.
+With --dry-run the queries run but nothing is posted, the windows of each SLI are listed as sli ingest run --dry-run does.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var state *ingest.BackfillState
+			var err error
+			if resume != "" {
+				if state, err = ingest.LoadBackfillState(resume); err != nil {
+					log.Fatalf("unable to resume backfill: \n%+v", err)
+				}
+				for _, flag := range []string{"sli", "all", "from", "to", "step", "org-id"} {
+					if cmd.Flags().Changed(flag) {
+						log.Fatalf("--%s can not be changed on --resume, the backfill continues as it started", flag)
+					}
+				}
+				if statePath == "" {
+					statePath = resume
+				}
+			} else {
+				orgId := utils.IntInput(cmd, "org-id", "Org ID")
+				start, end := timeRange(from, to)
+				ids := selectedSliIds(orgId, "sli", sliIds, all, "backfill")
+				if state, err = ingest.NewBackfillState(orgId, ids, start, end, step); err != nil {
+					log.Fatalf("unable to plan backfill: \n%+v", err)
+				}
+			}
+
+			if dryRun || dryRunFile != "" {
+				if dryRunFile != "" && len(state.SliIds) > 1 {
+					log.Fatalf("--dry-run-file takes the payload of a single SLI, %d are selected", len(state.SliIds))
+				}
+				p := clients.NewPrometheusClient()
+				for _, sli := range backfillSlis(state) {
+					preview, err := ingest.PreviewBackfill(p, sli, state)
+					if err != nil {
+						log.Fatalf("unable to preview backfill of SLI %d: \n%+v", sli.Id, err)
+					}
+					renderPreview(cmd, preview, dryRunFile)
+				}
+				return
+			}
+
+			runBackfill(cmd, state, statePath, concurrency)
+			if resume != "" {
+				// The state of a finished backfill is of no further use
+				if err := os.Remove(resume); err != nil && !errors.Is(err, os.ErrNotExist) {
+					slog.Warn("unable to remove backfill state", "file", resume, "error", err)
+				}
+			}
+		},
+	}
+	addOrgIdFlag(backfill)
+	addSliSelectionFlags(backfill, "sli", &sliIds, &all, "backfill")
+	backfill.Flags().StringVar(&from, "from", "", "start of the range, a time, date or duration before now, defaults to ingest.backfill days")
+	defaultFromConfig(backfill, "from", func(env *config.Config) string { return fmt.Sprintf("%dd", env.Ingest.Backfill) })
+	backfill.Flags().StringVar(&to, "to", "now", "end of the range, a time, date, now or duration before now")
+	backfill.Flags().DurationVar(&step, "step", 0, "query resolution, defaults to ingest.step")
+	defaultFromConfig(backfill, "step", func(env *config.Config) string { return fmt.Sprintf("%ds", env.Ingest.Step) })
+	backfill.Flags().IntVar(&concurrency, "concurrency", defaultBackfillConcurrency, "windows to query and post at once")
+	backfill.Flags().StringVar(&statePath, "state", "", "file to keep the state of a failed run in, defaults to backfill-<time>.json")
+	backfill.Flags().StringVar(&resume, "resume", "", "state file of a failed run to continue")
+	backfill.Flags().BoolVar(&dryRun, "dry-run", false, "run the queries and show what would be posted without posting")
+	backfill.Flags().StringVar(&dryRunFile, "dry-run-file", "", "write the full payload of a dry run of a single SLI to this file, implies --dry-run")
+
+	return backfill
+}
+
+// defaultBackfillConcurrency is how many windows a backfill queries and posts at once
+const defaultBackfillConcurrency = 2
+
+// backfillSlis fetches the SLIs of a backfill
+func backfillSlis(state *ingest.BackfillState) []*models.SliBody {
+	slis := []*models.SliBody{}
+	for _, id := range state.SliIds {
+		resp, err := models.GetSli(&models.GetSliRequest{OrgId: state.OrgId, Id: id})
+		if err != nil {
+			log.Fatalf("unable to fetch SLI %d: \n%+v", id, err)
+		}
+		slis = append(slis, resp.Sli)
+	}
+	return slis
+}
+
+// runBackfill backfills the pending windows of state with progress on stderr and prints
+// a summary per SLI. A failed or interrupted run saves state to statePath and exits.
+func runBackfill(cmd *cobra.Command, state *ingest.BackfillState, statePath string, concurrency int) {
+	if statePath == "" {
+		statePath = fmt.Sprintf("backfill-%d.json", time.Now().Unix())
+	}
+
+	slis := backfillSlis(state)
+	summaries := map[int]*sliBackfill{}
+	total := 0
+	for _, sli := range slis {
+		windows := len(state.Windows())
+		pending := len(state.Pending(sli.Id))
+		summaries[sli.Id] = &sliBackfill{SliId: sli.Id, Sli: sli.Name, Windows: windows, Done: windows - pending}
+		total += pending
+	}
+
+	// An interrupt stops new windows from starting, the running ones finish before the
+	// state is saved like that of a failed run. A second interrupt quits right away.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	interrupt := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		select {
+		case <-signals:
+			signal.Stop(signals)
+			fmt.Fprintln(os.Stderr, "\nInterrupted, waiting for running windows to finish")
+			close(interrupt)
+		case <-finished:
+		}
+	}()
+
+	// Batches queued while Blameless is unavailable are replayed as the backfill goes on
+	stopFlusher := startOutboxFlusher()
+	progress := output.NewProgress("backfill", total)
+	err := ingest.BackfillRange(clients.NewPrometheusClient(), slis, state, concurrency, interrupt, func(r *ingest.BackfillResult) {
+		s := summaries[r.SliId]
+		if r.Err != nil {
+			s.Error = r.Err.Error()
+		} else {
+			s.Done++
+		}
+		s.Points += r.Points
+		progress.Add(r.Err != nil)
+	})
+	progress.Finish()
+	stopFlusher()
+	signal.Stop(signals)
+	close(finished)
+
+	t := output.NewTable("SLI", "Name", "Windows", "Points", "Status")
+	results := []*sliBackfill{}
+	for _, id := range state.SliIds {
+		s := summaries[id]
+		status := "done"
+		switch {
+		case s.Error != "":
+			// The error itself follows the summary
+			status = "failed"
+		case s.Done < s.Windows:
+			status = "pending"
+		}
+		t.AddRow(s.SliId, s.Sli, fmt.Sprintf("%d/%d", s.Done, s.Windows), s.Points, status)
+		results = append(results, s)
+	}
+	render(cmd, results, t)
+
+	if errors.Is(err, ingest.ErrInterrupted) {
+		saveBackfillState(cmd, state, statePath)
+		os.Exit(130)
+	}
+	if err != nil {
+		saveBackfillState(cmd, state, statePath)
+		log.Fatalf("unable to backfill: \n%+v", err)
+	}
+}
+
+// saveBackfillState keeps the state of an unfinished backfill and tells how to resume it
+func saveBackfillState(cmd *cobra.Command, state *ingest.BackfillState, path string) {
+	if err := state.Save(path); err != nil {
+		log.Fatalf("unable to save backfill state to %s: \n%+v", path, err)
+	}
+	fmt.Fprintf(os.Stderr, "Backfill state saved, resume with:\n  %s sli ingest backfill --resume %s\n", cmd.Root().Name(), path)
 }
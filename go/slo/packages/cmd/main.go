@@ -3,6 +3,8 @@ package cmd
 import (
 	"log"
 
+	"github.com/blamelesshq/blameless-examples/slo/packages/config"
+	"github.com/blamelesshq/blameless-examples/slo/packages/metrics"
 	"github.com/spf13/cobra"
 )
 
@@ -11,11 +13,46 @@ func Execute() {
 		Use:   "cli",
 		Short: "Command line interface for SLO API example",
 		Long:  `Command line interface for SLO API example`,
+		// Reject a bad --output before any request is made
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			printer(cmd)
+			applyConfigDefaults(cmd)
+			serveMetrics(cmd)
+		},
 	}
+	addOutputFlag(rootCmd)
+	rootCmd.PersistentFlags().String("metrics-addr", "", "serve circuit breaker state on this address under /debug/vars, defaults to metrics.addr")
 
 	rootCmd.AddCommand(sli())
+	rootCmd.AddCommand(slo())
+	rootCmd.AddCommand(outboxCmd())
+	rootCmd.AddCommand(apply())
+	rootCmd.AddCommand(diff())
+	rootCmd.AddCommand(opensloCmd())
+	rootCmd.AddCommand(slothCmd())
+	rootCmd.AddCommand(rulesCmd())
+	rootCmd.AddCommand(budgetCmd())
+	rootCmd.AddCommand(reconcileCmd())
+	rootCmd.AddCommand(queryCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		log.Fatalf("unable to start command line \n%+v", err)
 	}
 }
+
+// serveMetrics starts the metrics server when --metrics-addr or metrics.addr is set
+func serveMetrics(cmd *cobra.Command) {
+	addr, err := cmd.Flags().GetString("metrics-addr")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !cmd.Flags().Changed("metrics-addr") {
+		addr = config.Environment().Metrics.Addr
+	}
+	if addr == "" {
+		return
+	}
+	if err := metrics.Serve(addr); err != nil {
+		log.Fatalf("unable to serve metrics: \n%+v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/config"
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+	"github.com/blamelesshq/blameless-examples/slo/packages/openslo"
+	"github.com/blamelesshq/blameless-examples/slo/packages/output"
+	"github.com/blamelesshq/blameless-examples/slo/packages/utils"
+	"github.com/spf13/cobra"
+)
+
+func opensloCmd() *cobra.Command {
+	o := &cobra.Command{
+		Use:   "openslo",
+		Short: "OpenSLO domain primary command",
+		Long:  `Translate OpenSLO v1 SLI, SLO and DataSource documents to and from Blameless`,
+	}
+
+	o.AddCommand(opensloImport())
+	o.AddCommand(opensloExport())
+
+	return o
+}
+
+func opensloImport() *cobra.Command {
+	var file string
+	var prune bool
+	var dryRun bool
+	var serviceIds map[string]int
+	var serviceId int
+	imp := &cobra.Command{
+		Use:   "import",
+		Short: "Import OpenSLO documents",
+		Long: `Create or update the SLIs and SLOs described by OpenSLO documents, the same way apply converges a manifest.
+Ratio metrics become availability SLIs and threshold metrics latency SLIs. Only Prometheus metric sources are read.
+OpenSLO services are mapped to Blameless service IDs with --service, names exported as service-<id> map back on their own.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var data []byte
+			var err error
+			if file == "-" {
+				data, err = io.ReadAll(os.Stdin)
+			} else {
+				data, err = os.ReadFile(file)
+			}
+			if err != nil {
+				log.Fatalf("unable to read OpenSLO documents: \n%+v", err)
+			}
+			docs, err := openslo.Parse(data)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			m, err := openslo.Import(docs, openslo.ServiceMap(serviceIds, serviceId))
+			if err != nil {
+				log.Fatalf("unable to translate OpenSLO documents: %v", err)
+			}
+			applyManifest(cmd, m, utils.IntInput(cmd, "org-id", "Org ID"), prune, dryRun)
+		},
+	}
+	addManifestFlags(imp, &file, &prune)
+	imp.Flags().Lookup("filename").Usage = "OpenSLO YAML documents, - reads stdin"
+	imp.Flags().StringToIntVar(&serviceIds, "service", map[string]int{}, "map an OpenSLO service to a Blameless service ID as <name>=<id>, repeatable")
+	imp.Flags().IntVar(&serviceId, "service-id", 0, "Blameless service ID for OpenSLO services without a mapping")
+	imp.Flags().BoolVar(&dryRun, "dry-run", false, "print the planned changes without making them")
+	addYesFlag(imp)
+
+	return imp
+}
+
+func opensloExport() *cobra.Command {
+	var sliIds []int
+	var all bool
+	var dir string
+	export := &cobra.Command{
+		Use:   "export",
+		Short: "Export SLIs as OpenSLO documents",
+		Long: `Write availability and latency SLIs and the SLOs built on them as OpenSLO v1 documents.
+Without --dir every document goes to stdout, with it each SLI is written to its own file.
+Names that map to the metadata name of an earlier SLI or SLO, such as "API Latency" after
+"api-latency", are suffixed with their Blameless ID.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			orgId := utils.IntInput(cmd, "org-id", "Org ID")
+			if all {
+				slis, err := models.ListAllSlis(&models.ListSlisRequest{OrgId: orgId})
+				if err != nil {
+					log.Fatalf("unable to list SLIs: \n%+v", err)
+				}
+				sliIds = []int{}
+				for _, s := range slis {
+					sliIds = append(sliIds, s.Id)
+				}
+			}
+			if len(sliIds) == 0 {
+				log.Fatal("provide SLIs to export with --sli-id or --all")
+			}
+
+			env := config.Environment().Prometheus
+			dataSource := openslo.DataSource(fmt.Sprintf("%s:%d", env.Host, env.Port))
+			stdout := []*openslo.Document{dataSource}
+			written := []*exportedFile{}
+			names := openslo.NewNames()
+			for _, id := range sliIds {
+				resp, err := models.GetSli(&models.GetSliRequest{OrgId: orgId, Id: id})
+				if err != nil {
+					log.Fatalf("unable to fetch SLI %d: \n%+v", id, err)
+				}
+				st, err := resp.Sli.GetSliType()
+				if err != nil {
+					log.Fatalf("unable to get SLI type: \n%+v", err)
+				}
+				slos, err := models.ListAllSlos(&models.ListSlosRequest{OrgId: orgId, SliId: id})
+				if err != nil {
+					log.Fatalf("unable to list SLOs of SLI %d: \n%+v", id, err)
+				}
+				docs, err := openslo.Export(resp.Sli, st.SliType.Name, slos, names)
+				if err != nil {
+					slog.Warn("skipping sli", "sliId", id, "reason", err)
+					continue
+				}
+				if dir == "" {
+					stdout = append(stdout, docs...)
+					continue
+				}
+				// SLI metadata names are unique within the export, so files are never overwritten
+				path := filepath.Join(dir, docs[0].Metadata.Name+".yaml")
+				if err := writeOpenslo(path, append([]*openslo.Document{dataSource}, docs...)); err != nil {
+					log.Fatalf("unable to write %s: \n%+v", path, err)
+				}
+				written = append(written, &exportedFile{File: path, Sli: resp.Sli.Name, Slos: len(slos)})
+			}
+
+			if dir == "" {
+				if err := openslo.Write(os.Stdout, stdout); err != nil {
+					log.Fatalf("unable to write OpenSLO documents: \n%+v", err)
+				}
+				return
+			}
+			t := output.NewTable("File", "SLI", "SLOs")
+			for _, f := range written {
+				t.AddRow(f.File, f.Sli, f.Slos)
+			}
+			render(cmd, written, t)
+		},
+	}
+	addOrgIdFlag(export)
+	export.Flags().IntSliceVar(&sliIds, "sli-id", []int{}, "SLI to export, repeatable")
+	export.Flags().BoolVar(&all, "all", false, "export every SLI of the org")
+	export.Flags().StringVar(&dir, "dir", "", "write one file per SLI into this directory")
+
+	return export
+}
+
+type exportedFile struct {
+	File string `json:"file"`
+	Sli  string `json:"sli"`
+	Slos int    `json:"slos"`
+}
+
+func writeOpenslo(path string, docs []*openslo.Document) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := openslo.Write(f, docs); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
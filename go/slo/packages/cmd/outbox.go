@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/clients"
+	"github.com/blamelesshq/blameless-examples/slo/packages/config"
+	"github.com/blamelesshq/blameless-examples/slo/packages/outbox"
+	"github.com/blamelesshq/blameless-examples/slo/packages/output"
+	"github.com/spf13/cobra"
+)
+
+func outboxCmd() *cobra.Command {
+	o := &cobra.Command{
+		Use:   "outbox",
+		Short: "Outbox domain primary command",
+		Long:  `Inspect, replay or drop raw data batches that failed to post to Blameless`,
+	}
+
+	o.AddCommand(outboxList())
+	o.AddCommand(outboxShow())
+	o.AddCommand(outboxReplay())
+	o.AddCommand(outboxDrop())
+
+	return o
+}
+
+func openOutbox() *outbox.Outbox {
+	box, err := outbox.Default()
+	if err != nil {
+		log.Fatalf("unable to open outbox: \n%+v", err)
+	}
+	return box
+}
+
+// startOutboxFlusher replays the outbox every outbox.flushInterval while a long running
+// command works, the returned function stops it. An interval of 0 disables it.
+func startOutboxFlusher() context.CancelFunc {
+	ctx, stop := context.WithCancel(context.Background())
+	interval := time.Duration(config.Environment().Outbox.FlushInterval) * time.Second
+	if interval > 0 {
+		openOutbox().StartFlusher(ctx, interval, outbox.BlamelessSender(clients.NewBlamelessClient()))
+	}
+	return stop
+}
+
+func batchTable(batches ...*outbox.Batch) *output.Table {
+	t := output.NewTable("ID", "Created", "SLI Type", "Points", "Attempts", "Last Error")
+	for _, b := range batches {
+		t.AddRow(b.Id,
+			b.Created.Format("2006-01-02 15:04:05"),
+			b.Request.SliType,
+			len(b.Request.RawData),
+			b.Attempts,
+			b.LastError,
+		)
+	}
+	return t
+}
+
+func outboxList() *cobra.Command {
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List queued batches",
+		Long:  `List queued raw data batches, oldest first`,
+		Run: func(cmd *cobra.Command, args []string) {
+			batches, err := openOutbox().List()
+			if err != nil {
+				log.Fatalf("unable to list outbox: \n%+v", err)
+			}
+			render(cmd, batches, batchTable(batches...))
+		},
+	}
+
+	return list
+}
+
+func outboxShow() *cobra.Command {
+	show := &cobra.Command{
+		Use:   "show <batch id>",
+		Short: "Show a queued batch",
+		Long:  `Print the raw data points of a queued batch, use --output json for the whole batch`,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			batch, err := openOutbox().Get(args[0])
+			if err != nil {
+				log.Fatalf("unable to read batch: \n%+v", err)
+			}
+			t := output.NewTable("SLI ID", "Start", "End", "Latency", "Good Request", "Valid Request", "Throughput", "Saturation", "Correctness", "Durability")
+			for _, d := range batch.Request.RawData {
+				t.AddRow(d.SliId, d.Start, d.End, d.Latency, d.GoodRequest, d.ValidRequest, d.Throughput, d.Saturation, d.Correctness, d.Durability)
+			}
+			render(cmd, batch, t)
+		},
+	}
+
+	return show
+}
+
+func outboxReplay() *cobra.Command {
+	replay := &cobra.Command{
+		Use:   "replay [batch id...]",
+		Short: "Replay queued batches",
+		Long:  `Post queued batches to Blameless oldest first, all of them unless batch ids are given`,
+		Run: func(cmd *cobra.Command, args []string) {
+			send := outbox.BlamelessSender(clients.NewBlamelessClient())
+			delivered, err := openOutbox().Replay(send, args...)
+			t := output.NewTable("Delivered")
+			t.AddRow(delivered)
+			render(cmd, map[string]int{"delivered": delivered}, t)
+			if err != nil {
+				log.Fatalf("replay stopped: \n%+v", err)
+			}
+		},
+	}
+
+	return replay
+}
+
+func outboxDrop() *cobra.Command {
+	var all bool
+	drop := &cobra.Command{
+		Use:   "drop [batch id...]",
+		Short: "Drop queued batches",
+		Long:  `Permanently discard queued batches without posting them`,
+		Run: func(cmd *cobra.Command, args []string) {
+			box := openOutbox()
+			ids := args
+			if all {
+				batches, err := box.List()
+				if err != nil {
+					log.Fatalf("unable to list outbox: \n%+v", err)
+				}
+				ids = []string{}
+				for _, b := range batches {
+					ids = append(ids, b.Id)
+				}
+			}
+			if len(ids) == 0 {
+				log.Fatal("provide batch ids to drop or --all")
+			}
+			t := output.NewTable("Dropped")
+			dropped := []string{}
+			var err error
+			for _, id := range ids {
+				if err = box.Drop(id); err != nil {
+					break
+				}
+				t.AddRow(id)
+				dropped = append(dropped, id)
+			}
+			render(cmd, dropped, t)
+			if err != nil {
+				log.Fatalf("%+v", err)
+			}
+		},
+	}
+	drop.Flags().BoolVar(&all, "all", false, "drop every queued batch")
+
+	return drop
+}
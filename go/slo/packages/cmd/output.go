@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/output"
+	"github.com/spf13/cobra"
+)
+
+// addOutputFlag registers the global --output flag on the root command
+func addOutputFlag(c *cobra.Command) {
+	c.PersistentFlags().StringP("output", "o", output.Formats.Table, "output format: table, json, yaml, csv or template=<go template>")
+}
+
+func printer(cmd *cobra.Command) *output.Printer {
+	spec, err := cmd.Flags().GetString("output")
+	if err != nil {
+		log.Fatal(err)
+	}
+	p, err := output.New(spec, os.Stdout)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	return p
+}
+
+// render prints a command result in the format chosen with --output
+func render(cmd *cobra.Command, data interface{}, table *output.Table) {
+	if err := printer(cmd).Print(data, table); err != nil {
+		log.Fatalf("unable to print output: \n%+v", err)
+	}
+}
+
+type deleted struct {
+	Kind    string `json:"kind"`
+	Id      int    `json:"id"`
+	Deleted bool   `json:"deleted"`
+}
+
+func renderDeleted(cmd *cobra.Command, kind string, id int) {
+	t := output.NewTable("Kind", "ID", "Deleted")
+	t.AddRow(kind, id, true)
+	render(cmd, &deleted{Kind: kind, Id: id, Deleted: true}, t)
+}
+
+// aborted reports a declined confirmation on stderr so stdout stays parseable
+func aborted() {
+	fmt.Fprintln(os.Stderr, "Aborted")
+}
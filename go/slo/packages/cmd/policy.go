@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+	"github.com/blamelesshq/blameless-examples/slo/packages/output"
+	"github.com/blamelesshq/blameless-examples/slo/packages/utils"
+	"github.com/spf13/cobra"
+)
+
+func policy() *cobra.Command {
+	policy := &cobra.Command{
+		Use:   "policy",
+		Short: "Error budget policy commands",
+		Long:  `Error budget policies list the actions to take as an SLO spends its error budget`,
+	}
+
+	policy.AddCommand(policyCreate())
+	policy.AddCommand(policyGet())
+	policy.AddCommand(policyUpdate())
+	policy.AddCommand(policyDelete())
+	policy.AddCommand(policyList())
+
+	return policy
+}
+
+func policyTable(policies ...*models.ErrorBudgetPolicyBody) *output.Table {
+	t := output.NewTable("Org ID", "ID", "Name", "Description", "Actions")
+	for _, p := range policies {
+		actions := make([]string, len(p.Actions))
+		for i, a := range p.Actions {
+			actions[i] = fmt.Sprintf("%s%%: %s", formatFloat(a.ConsumedPercentage), a.Action)
+		}
+		t.AddRow(p.OrgId,
+			p.Id,
+			p.Name,
+			p.Description,
+			strings.Join(actions, "; "),
+		)
+	}
+	return t
+}
+
+// policyInput reads the policy details. Actions given as --action replace the current
+// ones, otherwise existing actions are offered one by one before new ones are added.
+func policyInput(cmd *cobra.Command, current *models.ErrorBudgetPolicyBody) *models.ErrorBudgetPolicyBody {
+	p := *current
+	p.Name = utils.StringInputDefault(cmd, "name", "Name", current.Name)
+	p.Description = utils.StringInputDefault(cmd, "description", "Description", current.Description)
+
+	if cmd.Flags().Changed("action") {
+		flags, _ := cmd.Flags().GetStringArray("action")
+		p.Actions = make([]models.ErrorBudgetPolicyAction, len(flags))
+		for i, f := range flags {
+			action, err := parseAction(f)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			p.Actions[i] = action
+		}
+		return &p
+	}
+	if !utils.IsInteractive() {
+		return &p
+	}
+
+	p.Actions = []models.ErrorBudgetPolicyAction{}
+	for _, a := range current.Actions {
+		if !utils.ConfirmPrompt(fmt.Sprintf("Keep action at %s%% (%s)", formatFloat(a.ConsumedPercentage), a.Action)) {
+			continue
+		}
+		p.Actions = append(p.Actions, models.ErrorBudgetPolicyAction{
+			ConsumedPercentage: utils.FloatPromptDefault("Budget Consumed %", formatFloat(a.ConsumedPercentage)),
+			Action:             utils.StringPromptDefault("Action", a.Action),
+		})
+	}
+	for len(p.Actions) == 0 || utils.ConfirmPrompt("Add another action") {
+		p.Actions = append(p.Actions, models.ErrorBudgetPolicyAction{
+			ConsumedPercentage: utils.FloatPrompt("Budget Consumed %"),
+			Action:             utils.StringPrompt("Action"),
+		})
+	}
+	return &p
+}
+
+// parseAction reads an action flag of the form <consumed percentage>=<action>
+func parseAction(flag string) (models.ErrorBudgetPolicyAction, error) {
+	parts := strings.SplitN(flag, "=", 2)
+	if len(parts) != 2 {
+		return models.ErrorBudgetPolicyAction{}, fmt.Errorf("action %q must look like 50=Freeze feature releases", flag)
+	}
+	consumed, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return models.ErrorBudgetPolicyAction{}, fmt.Errorf("action %q does not start with a percentage", flag)
+	}
+	return models.ErrorBudgetPolicyAction{
+		ConsumedPercentage: consumed,
+		Action:             strings.TrimSpace(parts[1]),
+	}, nil
+}
+
+// validatePolicy exits on an invalid policy, naming the flag that fills a missing field
+func validatePolicy(body *models.ErrorBudgetPolicyBody) {
+	err := body.Validate()
+	switch {
+	case err == nil:
+		return
+	case errors.Is(err, models.ErrPolicyNoName):
+		log.Fatalf("invalid error budget policy: %v, set --name", err)
+	case errors.Is(err, models.ErrPolicyNoActions):
+		log.Fatalf("invalid error budget policy: %v, set --action", err)
+	}
+	log.Fatalf("invalid error budget policy: %v", err)
+}
+
+func addPolicyFlags(c *cobra.Command) {
+	c.Flags().String("name", "", "policy name")
+	c.Flags().String("description", "", "policy description")
+	c.Flags().StringArray("action", []string{}, "action as <budget consumed %>=<action>, repeatable")
+}
+
+func policyCreate() *cobra.Command {
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "Create a new error budget policy",
+		Long:  `Create a new error budget policy`,
+		Run: func(cmd *cobra.Command, args []string) {
+			orgId := utils.IntInput(cmd, "org-id", "Org ID")
+			body := policyInput(cmd, &models.ErrorBudgetPolicyBody{})
+			validatePolicy(body)
+			resp, err := models.PostErrorBudgetPolicy(&models.PostErrorBudgetPolicyRequest{
+				OrgId: orgId,
+				Model: body,
+			})
+			if err != nil {
+				log.Fatalf("unable to create error budget policy: \n%+v", err)
+			}
+			render(cmd, resp.ErrorBudgetPolicy, policyTable(resp.ErrorBudgetPolicy))
+		},
+	}
+	addOrgIdFlag(create)
+	addPolicyFlags(create)
+
+	return create
+}
+
+func policyGet() *cobra.Command {
+	get := &cobra.Command{
+		Use:   "get",
+		Short: "get an error budget policy",
+		Long:  `get an error budget policy`,
+		Run: func(cmd *cobra.Command, args []string) {
+			resp, err := models.GetErrorBudgetPolicy(&models.GetErrorBudgetPolicyRequest{
+				OrgId: utils.IntInput(cmd, "org-id", "Org ID"),
+				Id:    utils.IntInput(cmd, "policy-id", "Policy ID"),
+			})
+			if err != nil {
+				log.Fatalf("unable to complete request: \n%+v", err)
+			}
+			render(cmd, resp.ErrorBudgetPolicy, policyTable(resp.ErrorBudgetPolicy))
+		},
+	}
+	addOrgIdFlag(get)
+	get.Flags().Int("policy-id", 0, "policy to get")
+
+	return get
+}
+
+func policyUpdate() *cobra.Command {
+	update := &cobra.Command{
+		Use:   "update",
+		Short: "Update an error budget policy",
+		Long:  `Update the name, description and actions of an error budget policy`,
+		Run: func(cmd *cobra.Command, args []string) {
+			orgId := utils.IntInput(cmd, "org-id", "Org ID")
+			policyId := utils.IntInput(cmd, "policy-id", "Policy ID")
+			current, err := models.GetErrorBudgetPolicy(&models.GetErrorBudgetPolicyRequest{
+				OrgId: orgId,
+				Id:    policyId,
+			})
+			if err != nil {
+				log.Fatalf("unable to fetch error budget policy: \n%+v", err)
+			}
+			body := policyInput(cmd, current.ErrorBudgetPolicy)
+			validatePolicy(body)
+			resp, err := models.UpdateErrorBudgetPolicy(&models.UpdateErrorBudgetPolicyRequest{
+				OrgId: orgId,
+				Id:    policyId,
+				Model: body,
+			})
+			if err != nil {
+				log.Fatalf("unable to update error budget policy: \n%+v", err)
+			}
+			render(cmd, resp.ErrorBudgetPolicy, policyTable(resp.ErrorBudgetPolicy))
+		},
+	}
+	addOrgIdFlag(update)
+	update.Flags().Int("policy-id", 0, "policy to update")
+	addPolicyFlags(update)
+
+	return update
+}
+
+func policyDelete() *cobra.Command {
+	del := &cobra.Command{
+		Use:   "delete",
+		Short: "Delete an error budget policy",
+		Long:  `Delete an error budget policy, SLOs linked to it are kept`,
+		Run: func(cmd *cobra.Command, args []string) {
+			orgId := utils.IntInput(cmd, "org-id", "Org ID")
+			policyId := utils.IntInput(cmd, "policy-id", "Policy ID")
+			if !utils.ConfirmInput(cmd, "yes", fmt.Sprintf("Delete error budget policy %d", policyId)) {
+				aborted()
+				return
+			}
+			if _, err := models.DeleteErrorBudgetPolicy(&models.DeleteErrorBudgetPolicyRequest{
+				OrgId: orgId,
+				Id:    policyId,
+			}); err != nil {
+				log.Fatalf("unable to delete error budget policy: \n%+v", err)
+			}
+			renderDeleted(cmd, "errorBudgetPolicy", policyId)
+		},
+	}
+	addOrgIdFlag(del)
+	del.Flags().Int("policy-id", 0, "policy to delete")
+	addYesFlag(del)
+
+	return del
+}
+
+func policyList() *cobra.Command {
+	req := &models.ListErrorBudgetPoliciesRequest{}
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List error budget policies",
+		Long:  `List error budget policies`,
+		Run: func(cmd *cobra.Command, args []string) {
+			req.OrgId = utils.IntInput(cmd, "org-id", "Org ID")
+			resp, err := models.ListErrorBudgetPolicies(req)
+			if err != nil {
+				log.Fatalf("unable to list error budget policies: \n%+v", err)
+			}
+			render(cmd, resp.ErrorBudgetPolicies, policyTable(resp.ErrorBudgetPolicies...))
+		},
+	}
+	addOrgIdFlag(list)
+	list.Flags().IntVar(&req.Page, "page", 1, "page to list")
+	list.Flags().IntVar(&req.PageSize, "page-size", models.DefaultPageSize, "policies per page")
+
+	return list
+}
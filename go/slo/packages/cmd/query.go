@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/clients"
+	"github.com/blamelesshq/blameless-examples/slo/packages/output"
+	"github.com/blamelesshq/blameless-examples/slo/packages/query"
+	"github.com/blamelesshq/blameless-examples/slo/packages/utils"
+	"github.com/spf13/cobra"
+)
+
+// queryCheckRange is how far back sli create runs entered queries
+const queryCheckRange = time.Hour
+
+func queryCmd() *cobra.Command {
+	q := &cobra.Command{
+		Use:   "query",
+		Short: "PromQL query domain primary command",
+		Long:  `Try SLI queries against Prometheus before creating SLIs on them`,
+	}
+
+	q.AddCommand(queryTest())
+
+	return q
+}
+
+func queryTest() *cobra.Command {
+	var lookback string
+	var samples int
+	test := &cobra.Command{
+		Use:   "test",
+		Short: "Run a PromQL query over a recent range",
+		Long: `Run a query against Prometheus at ingest.step resolution over a recent range, the way ingest runs SLI queries.
+Each series is shown with its labels, point count, a sparkline and its latest samples.
+Queries returning no series, more than one series or no sample with a value are rejected with exit code 1.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			promql := utils.StringInput(cmd, "query", "Query")
+			d, err := utils.ParseDuration(lookback)
+			if err != nil {
+				log.Fatalf("unable to parse --range: \n%+v", err)
+			}
+			now := time.Now()
+			result, err := query.Test(clients.NewPrometheusClient(), promql, now.Add(-d), now, samples)
+			if err != nil {
+				log.Fatalf("unable to run query: \n%+v", err)
+			}
+			render(cmd, result, queryTable(result))
+			if err := result.Validate(); err != nil {
+				log.Fatalf("%v", err)
+			}
+		},
+	}
+	test.Flags().String("query", "", "PromQL query to test")
+	test.Flags().StringVar(&lookback, "range", "1h", "how far back to run the query, such as 30m or 1d")
+	test.Flags().IntVar(&samples, "samples", 5, "latest samples to show per series")
+
+	return test
+}
+
+func queryTable(result *query.Result) *output.Table {
+	t := output.NewTable("Series", "Labels", "Points", "NaN", "Min", "Max", "Last", "Sparkline", "Samples")
+	for i, s := range result.Series {
+		values := []string{}
+		for _, sample := range s.Samples {
+			values = append(values, sample.Value)
+		}
+		t.AddRow(i+1, formatLabels(s.Labels), s.Points, s.NaN, formatSample(s.Min), formatSample(s.Max), formatSample(s.Last), s.Sparkline, strings.Join(values, " "))
+	}
+	return t
+}
+
+// formatSample keeps six significant digits, enough to tell values apart at a glance
+func formatSample(f float64) string {
+	return strconv.FormatFloat(f, 'g', 6, 64)
+}
+
+// formatLabels writes labels the way PromQL selects them, sorted by name
+func formatLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf("%s=%q", name, labels[name])
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// checkedQueryInput reads a query like utils.StringInput and runs it over the last hour.
+// A query given as a flag that fails the check is fatal, a prompted one is asked again.
+func checkedQueryInput(cmd *cobra.Command, flag string, label string) string {
+	for {
+		promql := utils.StringInput(cmd, flag, label)
+		if skip, _ := cmd.Flags().GetBool("skip-query-check"); skip {
+			return promql
+		}
+		now := time.Now()
+		result, err := query.Test(clients.NewPrometheusClient(), promql, now.Add(-queryCheckRange), now, 3)
+		if err == nil {
+			err = result.Validate()
+		}
+		if err == nil {
+			s := result.Series[0]
+			fmt.Fprintf(os.Stderr, "%s %s %d points, last %s\n", formatLabels(s.Labels), s.Sparkline, s.Points, formatSample(s.Last))
+			return promql
+		}
+		if cmd.Flags().Changed(flag) || !utils.IsInteractive() {
+			log.Fatalf("%s failed its check, fix it or pass --skip-query-check: \n%+v", label, err)
+		}
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+	}
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/clients"
+	"github.com/blamelesshq/blameless-examples/slo/packages/config"
+	"github.com/blamelesshq/blameless-examples/slo/packages/ingest"
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+	"github.com/blamelesshq/blameless-examples/slo/packages/outbox"
+	"github.com/blamelesshq/blameless-examples/slo/packages/output"
+	"github.com/blamelesshq/blameless-examples/slo/packages/reconcile"
+	"github.com/blamelesshq/blameless-examples/slo/packages/utils"
+	"github.com/spf13/cobra"
+)
+
+// sliReport is the reconciliation of one SLI
+type sliReport struct {
+	Sli string `json:"sli"`
+	*reconcile.Report
+	Reposted int `json:"reposted"`
+}
+
+func reconcileCmd() *cobra.Command {
+	var sliIds []int
+	var all bool
+	var from string
+	var to string
+	var tolerance float64
+	var repost bool
+	r := &cobra.Command{
+		Use:   "reconcile",
+		Short: "Compare raw data stored in Blameless with Prometheus",
+		Long: `Read back the raw data stored for each SLI through the timeseries service and compare it, bucket by bucket,
+with a fresh Prometheus query over the same range. Points are matched by the ingest.step bucket they start in, the range is aligned to ingest.step.
+The report lists missing windows, buckets stored that Prometheus no longer returns, duplicates and value mismatches.
+With --repost the missing buckets are posted from the fresh query, failed batches are kept in the outbox.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			orgId := utils.IntInput(cmd, "org-id", "Org ID")
+			start, end := timeRange(from, to)
+			step := time.Duration(config.Environment().Ingest.Step) * time.Second
+			start, end = start.Truncate(step), end.Truncate(step)
+			sliIds = selectedSliIds(orgId, "sli-id", sliIds, all, "reconcile")
+
+			p := clients.NewPrometheusClient()
+			reports := []*sliReport{}
+			sliTypes := map[int]string{}
+			for _, id := range sliIds {
+				resp, err := models.GetSli(&models.GetSliRequest{OrgId: orgId, Id: id})
+				if err != nil {
+					log.Fatalf("unable to fetch SLI %d: \n%+v", id, err)
+				}
+				st, err := resp.Sli.GetSliType()
+				if err != nil {
+					log.Fatalf("unable to get SLI type: \n%+v", err)
+				}
+				expected, err := ingest.Fetch(p, resp.Sli, start, end)
+				if err != nil {
+					log.Fatalf("unable to query SLI %d from prometheus: \n%+v", id, err)
+				}
+				stored, err := models.GetAllRawData(&models.GetManyRequest{OrgId: orgId, SliId: id, Start: int(start.Unix()), End: int(end.Unix())})
+				if err != nil {
+					log.Fatalf("unable to read raw data of SLI %d: \n%+v", id, err)
+				}
+				sliTypes[id] = st.SliType.Name
+				reports = append(reports, &sliReport{
+					Sli:    resp.Sli.Name,
+					Report: reconcile.Compare(id, st.SliType.Name, start, end, step, expected, stored, tolerance),
+				})
+			}
+
+			missing := 0
+			for _, r := range reports {
+				missing += len(r.MissingData())
+			}
+			if repost && missing > 0 {
+				if !utils.ConfirmInput(cmd, "yes", fmt.Sprintf("Repost %d missing point(s)", missing)) {
+					aborted()
+					return
+				}
+				for _, r := range reports {
+					data := r.MissingData()
+					if len(data) == 0 {
+						continue
+					}
+					_, err := ingest.Post(sliTypes[r.SliId], data)
+					if err != nil && !errors.Is(err, outbox.ErrQueued) {
+						log.Fatalf("unable to repost SLI %d: \n%+v", r.SliId, err)
+					}
+					r.Reposted = len(data)
+				}
+			}
+			render(cmd, reports, reconcileTable(reports))
+		},
+	}
+	addOrgIdFlag(r)
+	addYesFlag(r)
+	addSliSelectionFlags(r, "sli-id", &sliIds, &all, "reconcile")
+	r.Flags().StringVar(&from, "from", "24h", "start of the range, RFC 3339, YYYY-MM-DD or a duration before now such as 6h or 7d")
+	r.Flags().StringVar(&to, "to", "now", "end of the range, in the same formats as --from")
+	r.Flags().Float64Var(&tolerance, "tolerance", 0, "relative difference a stored value may have from Prometheus, 0.01 allows 1%")
+	r.Flags().BoolVar(&repost, "repost", false, "post the missing buckets from the fresh Prometheus query")
+
+	return r
+}
+
+// reconcileTable lists one row per issue found, an SLI without issues gets a single ok row
+func reconcileTable(reports []*sliReport) *output.Table {
+	t := output.NewTable("SLI", "Issue", "Start", "End", "Points", "Field", "Stored", "Expected")
+	for _, r := range reports {
+		if r.Consistent() {
+			t.AddRow(r.Sli, "ok", formatUnix(int(r.From.Unix())), formatUnix(int(r.To.Unix())), r.Expected, "", "", "")
+			continue
+		}
+		for _, w := range r.Missing {
+			issue := "missing"
+			if r.Reposted > 0 {
+				issue = "missing, reposted"
+			}
+			t.AddRow(r.Sli, issue, formatUnix(w.Start), formatUnix(w.End), w.Points, "", "", "")
+		}
+		for _, w := range r.Extra {
+			t.AddRow(r.Sli, "extra", formatUnix(w.Start), formatUnix(w.End), w.Points, "", "", "")
+		}
+		for _, d := range r.Duplicates {
+			t.AddRow(r.Sli, "duplicate", formatUnix(d.Start), "", d.Count, "", "", "")
+		}
+		for _, m := range r.Mismatches {
+			t.AddRow(r.Sli, "mismatch", formatUnix(m.Start), "", 1, m.Field, m.Stored, m.Expected)
+		}
+	}
+	return t
+}
+
+func formatUnix(seconds int) string {
+	return time.Unix(int64(seconds), 0).UTC().Format("2006-01-02 15:04:05")
+}
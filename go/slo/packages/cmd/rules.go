@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/config"
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+	"github.com/blamelesshq/blameless-examples/slo/packages/output"
+	"github.com/blamelesshq/blameless-examples/slo/packages/rules"
+	"github.com/blamelesshq/blameless-examples/slo/packages/utils"
+	"github.com/spf13/cobra"
+)
+
+func rulesCmd() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "rules",
+		Short: "Prometheus rules domain primary command",
+		Long:  `Generate Prometheus rules that alert on the SLOs defined in Blameless`,
+	}
+
+	r.AddCommand(rulesGenerate())
+
+	return r
+}
+
+func rulesGenerate() *cobra.Command {
+	var sliIds []int
+	var all bool
+	var dir string
+	var resolution string
+	generate := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate recording and burn rate alert rules",
+		Long: fmt.Sprintf(`Write Prometheus rule files from SLI metric paths and SLO objectives.
+Each SLO gets its error ratio recorded over %v and multiwindow, multi-burn-rate page and ticket alerts.
+Availability ratios scale the range selectors of the good and valid queries, latency ratios count slices above the SLO threshold.
+Without --dir the rules go to stdout as one file, with it each SLI is written to sli-<id>.rules.yaml.`, rules.Windows),
+		Run: func(cmd *cobra.Command, args []string) {
+			orgId := utils.IntInput(cmd, "org-id", "Org ID")
+			sliIds = selectedSliIds(orgId, "sli-id", sliIds, all, "generate rules for")
+
+			combined := &rules.RuleFile{}
+			written := []*generatedRules{}
+			for _, id := range sliIds {
+				resp, err := models.GetSli(&models.GetSliRequest{OrgId: orgId, Id: id})
+				if err != nil {
+					log.Fatalf("unable to fetch SLI %d: \n%+v", id, err)
+				}
+				st, err := resp.Sli.GetSliType()
+				if err != nil {
+					log.Fatalf("unable to get SLI type: \n%+v", err)
+				}
+				slos, err := models.ListAllSlos(&models.ListSlosRequest{OrgId: orgId, SliId: id})
+				if err != nil {
+					log.Fatalf("unable to list SLOs of SLI %d: \n%+v", id, err)
+				}
+				file, err := rules.Generate(resp.Sli, st.SliType.Name, slos, resolution)
+				if err != nil {
+					slog.Warn("skipping sli", "sliId", id, "reason", err)
+					continue
+				}
+				if dir == "" {
+					combined.Groups = append(combined.Groups, file.Groups...)
+					continue
+				}
+				path := filepath.Join(dir, fmt.Sprintf("sli-%d.rules.yaml", id))
+				if err := writeRules(path, file); err != nil {
+					log.Fatalf("unable to write %s: \n%+v", path, err)
+				}
+				written = append(written, &generatedRules{File: path, Sli: resp.Sli.Name, Groups: len(file.Groups)})
+			}
+
+			if dir == "" {
+				if err := rules.Write(os.Stdout, combined); err != nil {
+					log.Fatalf("unable to write rules: \n%+v", err)
+				}
+				return
+			}
+			t := output.NewTable("File", "SLI", "Groups")
+			for _, g := range written {
+				t.AddRow(g.File, g.Sli, g.Groups)
+			}
+			render(cmd, written, t)
+		},
+	}
+	addOrgIdFlag(generate)
+	addSliSelectionFlags(generate, "sli-id", &sliIds, &all, "generate rules for")
+	generate.Flags().StringVar(&dir, "dir", "", "write one rule file per SLI into this directory")
+	generate.Flags().StringVar(&resolution, "resolution", "", "subquery resolution of latency ratios, defaults to ingest.step")
+	defaultFromConfig(generate, "resolution", func(env *config.Config) string { return fmt.Sprintf("%ds", env.Ingest.Step) })
+
+	return generate
+}
+
+type generatedRules struct {
+	File   string `json:"file"`
+	Sli    string `json:"sli"`
+	Groups int    `json:"groups"`
+}
+
+func writeRules(path string, file *rules.RuleFile) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := rules.Write(f, file); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
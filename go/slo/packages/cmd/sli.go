@@ -8,8 +8,8 @@ import (
 	"strings"
 
 	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+	"github.com/blamelesshq/blameless-examples/slo/packages/output"
 	"github.com/blamelesshq/blameless-examples/slo/packages/utils"
-	"github.com/cheynewallace/tabby"
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
 )
@@ -23,69 +23,16 @@ func sli() *cobra.Command {
 
 	sli.AddCommand(sliCreate())
 	sli.AddCommand(sliGet())
-	sli.AddCommand(ingest())
+	sli.AddCommand(sliUpdate())
+	sli.AddCommand(sliDelete())
+	sli.AddCommand(sliList())
+	sli.AddCommand(sliTypes())
+	sli.AddCommand(ingestCmd())
 
 	return sli
 }
 
-type Types struct {
-	Name string
-	Id   int
-}
-
-func intPrompt(label string) int {
-	validateInt := func(input string) error {
-		_, err := strconv.ParseFloat(input, 64)
-		if err != nil {
-			return fmt.Errorf("unable to parse integer for %s", label)
-		}
-		return nil
-	}
-
-	intP := promptui.Prompt{
-		Label:    label,
-		Validate: validateInt,
-	}
-	result, err := intP.Run()
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	id, err := strconv.Atoi(result)
-	if err != nil {
-		log.Fatal(err)
-	}
-	return id
-}
-
-func stringPrompt(label string) string {
-	validateString := func(input string) error {
-		if len(input) < 1 {
-			return fmt.Errorf("must provide at least one character for %s", label)
-		}
-		return nil
-	}
-	stringP := promptui.Prompt{
-		Label:    label,
-		Validate: validateString,
-	}
-	result, err := stringP.Run()
-	if err != nil {
-		log.Fatal(err)
-	}
-	return result
-}
-
 func sliCreate() *cobra.Command {
-	sliTypes := []Types{
-		{Name: "Availability", Id: 1},
-		{Name: "Latency", Id: 2},
-		{Name: "Throughput", Id: 3},
-		{Name: "Saturation", Id: 4},
-		{Name: "Durability", Id: 5},
-		{Name: "Correctness", Id: 6},
-	}
-
 	templates := &promptui.SelectTemplates{
 		Label:    "{{ . }}?",
 		Active:   "\U0001F336 {{ .Name | cyan }} ({{ .Id | white }})",
@@ -98,81 +45,26 @@ func sliCreate() *cobra.Command {
 		`,
 	}
 
-	searcher := func(input string, index int) bool {
-		t := sliTypes[index]
-		name := strings.Replace(strings.ToLower(t.Name), " ", "", -1)
-		input = strings.Replace(strings.ToLower(input), " ", "", -1)
-
-		return strings.Contains(name, input)
-	}
-
 	create := &cobra.Command{
 		Use:   "create",
 		Short: "Create a new SLI",
 		Long:  `Create a new SLI`,
 		Run: func(cmd *cobra.Command, args []string) {
-			orgId := utils.IntPrompt("Org ID")
-			name := utils.StringPrompt("Name")
-			description := stringPrompt("Description")
-			sliTypePrompt := promptui.Select{
-				Label:     "Sli Type",
-				Items:     sliTypes,
-				Templates: templates,
-				Size:      4,
-				Searcher:  searcher,
-			}
-			sliType, _, err := sliTypePrompt.Run()
-			if err != nil {
-				log.Fatalf("Unable to parse selection: \n%+v", err)
-			}
-			serviceId := intPrompt("Service ID")
+			orgId := utils.IntInput(cmd, "org-id", "Org ID")
+			name := utils.StringInput(cmd, "name", "Name")
+			description := utils.StringInput(cmd, "description", "Description")
+			sliType := sliTypeInput(cmd, templates)
+			serviceId := utils.IntInput(cmd, "service-id", "Service ID")
 
 			sliBody := &models.SliBody{
 				Name:         name,
 				Description:  description,
 				DataSourceId: 5,
-				SliTypeId:    sliType,
+				SliTypeId:    sliType.Id,
 				ServiceId:    serviceId,
 			}
 
-			metricPath := &models.MetricPath{}
-			switch t := sliType + 1; t {
-			case sliTypes[0].Id:
-				goodRequest := utils.StringPrompt("Good Request Query")
-				validRequest := utils.StringPrompt("Valid Request Query")
-				availability := &models.AvailabilityStruct{
-					GoodRequest:  goodRequest,
-					ValidRequest: validRequest,
-				}
-				metricPath = &models.MetricPath{
-					Availability: availability,
-				}
-			case sliTypes[1].Id:
-				latencyReq := utils.StringPrompt("Latency Query")
-				metricPath = &models.MetricPath{
-					Latency: latencyReq,
-				}
-			case sliTypes[2].Id:
-				throughputReq := utils.StringPrompt("Throughput Query")
-				metricPath = &models.MetricPath{
-					Throughput: throughputReq,
-				}
-			case sliTypes[3].Id:
-				saturationReq := utils.StringPrompt("Saturation Query")
-				metricPath = &models.MetricPath{
-					Saturation: saturationReq,
-				}
-			case sliTypes[4].Id:
-				durabilityReq := stringPrompt("Durability Query")
-				metricPath = &models.MetricPath{
-					Durability: durabilityReq,
-				}
-			case sliTypes[5].Id:
-				correctnessReq := stringPrompt("Correctness Query")
-				metricPath = &models.MetricPath{
-					Correctness: correctnessReq,
-				}
-			}
+			metricPath := metricPathInput(cmd, sliType.Name)
 			mp, err := json.Marshal(metricPath)
 			if err != nil {
 				log.Fatalf("error while marshaling metric path: %s", err)
@@ -186,22 +78,85 @@ func sliCreate() *cobra.Command {
 			if err != nil {
 				log.Fatalf("Unable to make regequest: \n%+v", err)
 			}
-			t := tabby.New()
-			t.AddHeader("Org ID", "ID", "Name", "Description", "Data Source ID", "SLI Type ID", "Service ID", "User ID")
-			t.AddLine(resp.Sli.Id,
-				resp.Sli.Name,
-				resp.Sli.Description,
-				resp.Sli.DataSourceId,
-				resp.Sli.SliTypeId,
-				resp.Sli.ServiceId,
-			)
-			t.Print()
+			render(cmd, resp.Sli, sliTable(resp.Sli))
 		},
 	}
+	addOrgIdFlag(create)
+	create.Flags().String("name", "", "SLI name")
+	create.Flags().String("description", "", "SLI description")
+	create.Flags().String("type", "", "SLI type name or ID, see sli types")
+	create.Flags().Int("service-id", 0, "service the SLI measures")
+	addQueryFlags(create)
+	create.Flags().Bool("skip-query-check", false, "create the SLI without running its queries against Prometheus")
 
 	return create
 }
 
+// sliTypeInput resolves --type against the live catalog by name or ID, or offers the catalog as a selection
+func sliTypeInput(cmd *cobra.Command, templates *promptui.SelectTemplates) *models.SliTypeBody {
+	catalog, err := models.CachedSliTypes()
+	if err != nil {
+		log.Fatalf("unable to list SLI types: \n%+v", err)
+	}
+	if flag, _ := cmd.Flags().GetString("type"); flag != "" {
+		for _, st := range catalog {
+			if strings.EqualFold(st.Name, flag) || strconv.Itoa(st.Id) == flag {
+				return st
+			}
+		}
+		log.Fatalf("unknown SLI type %q, see sli types", flag)
+	}
+	if !utils.IsInteractive() {
+		log.Fatal("missing SLI type, pass --type when not running in a terminal")
+	}
+
+	searcher := func(input string, index int) bool {
+		t := catalog[index]
+		name := strings.Replace(strings.ToLower(t.Name), " ", "", -1)
+		input = strings.Replace(strings.ToLower(input), " ", "", -1)
+
+		return strings.Contains(name, input)
+	}
+	sliTypePrompt := promptui.Select{
+		Label:     "Sli Type",
+		Items:     catalog,
+		Templates: templates,
+		Size:      4,
+		Searcher:  searcher,
+	}
+	selected, _, err := sliTypePrompt.Run()
+	if err != nil {
+		log.Fatalf("Unable to parse selection: \n%+v", err)
+	}
+	return catalog[selected]
+}
+
+// metricPathInput reads the queries the SLI type needs from the query flags or prompts,
+// each is run against Prometheus before the SLI is created
+func metricPathInput(cmd *cobra.Command, sliTypeName string) *models.MetricPath {
+	switch sliTypeName {
+	case models.Types.Availability:
+		return &models.MetricPath{
+			Availability: &models.AvailabilityStruct{
+				GoodRequest:  checkedQueryInput(cmd, "good-query", "Good Request Query"),
+				ValidRequest: checkedQueryInput(cmd, "valid-query", "Valid Request Query"),
+			},
+		}
+	case models.Types.Latency:
+		return &models.MetricPath{Latency: checkedQueryInput(cmd, "query", "Latency Query")}
+	case models.Types.Throughput:
+		return &models.MetricPath{Throughput: checkedQueryInput(cmd, "query", "Throughput Query")}
+	case models.Types.Saturation:
+		return &models.MetricPath{Saturation: checkedQueryInput(cmd, "query", "Saturation Query")}
+	case models.Types.Durability:
+		return &models.MetricPath{Durability: checkedQueryInput(cmd, "query", "Durability Query")}
+	case models.Types.Correctness:
+		return &models.MetricPath{Correctness: checkedQueryInput(cmd, "query", "Correctness Query")}
+	}
+	log.Fatalf("SLI type %s is not supported by this CLI", sliTypeName)
+	return nil
+}
+
 func sliGet() *cobra.Command {
 	get := &cobra.Command{
 		Use:   "get",
@@ -209,8 +164,8 @@ func sliGet() *cobra.Command {
 		Long:  `get a SLI`,
 		Run: func(cmd *cobra.Command, args []string) {
 			sliReq := &models.GetSliRequest{
-				OrgId: intPrompt("Org ID"),
-				Id:    intPrompt("SLI ID"),
+				OrgId: utils.IntInput(cmd, "org-id", "Org ID"),
+				Id:    utils.IntInput(cmd, "sli-id", "SLI ID"),
 			}
 			resp, err := models.GetSli(sliReq)
 			if err != nil {
@@ -220,9 +175,8 @@ func sliGet() *cobra.Command {
 			if err != nil {
 				log.Fatalf("unable to get SLI type: \n%+v", err)
 			}
-			t := tabby.New()
-			t.AddHeader("Org ID", "ID", "Name", "Description", "Data Source ID", "SLI Type ID", "SLI Type", "Service ID", "User ID")
-			t.AddLine(resp.Sli.OrgId,
+			t := output.NewTable("Org ID", "ID", "Name", "Description", "Data Source ID", "SLI Type ID", "SLI Type", "Service ID", "User ID")
+			t.AddRow(resp.Sli.OrgId,
 				resp.Sli.Id,
 				resp.Sli.Name,
 				resp.Sli.Description,
@@ -230,10 +184,198 @@ func sliGet() *cobra.Command {
 				resp.Sli.SliTypeId,
 				st.SliType.Name,
 				resp.Sli.ServiceId,
+				resp.Sli.UserId,
 			)
-			t.Print()
+			render(cmd, &sliWithType{SliBody: resp.Sli, SliType: st.SliType.Name}, t)
 		},
 	}
+	addOrgIdFlag(get)
+	get.Flags().Int("sli-id", 0, "SLI to get")
 
 	return get
 }
+
+// sliWithType is an SLI printed together with the name of its type
+type sliWithType struct {
+	*models.SliBody
+	SliType string `json:"sliType"`
+}
+
+func sliTable(slis ...*models.SliBody) *output.Table {
+	t := output.NewTable("Org ID", "ID", "Name", "Description", "Data Source ID", "SLI Type ID", "Service ID", "User ID")
+	for _, s := range slis {
+		t.AddRow(s.OrgId,
+			s.Id,
+			s.Name,
+			s.Description,
+			s.DataSourceId,
+			s.SliTypeId,
+			s.ServiceId,
+			s.UserId,
+		)
+	}
+	return t
+}
+
+// metricPathInputDefault offers every query already set on the metric path for editing, flags replace them
+func metricPathInputDefault(cmd *cobra.Command, mp *models.MetricPath) *models.MetricPath {
+	updated := &models.MetricPath{}
+	if mp.Availability != nil {
+		updated.Availability = &models.AvailabilityStruct{
+			GoodRequest:  utils.StringInputDefault(cmd, "good-query", "Good Request Query", mp.Availability.GoodRequest),
+			ValidRequest: utils.StringInputDefault(cmd, "valid-query", "Valid Request Query", mp.Availability.ValidRequest),
+		}
+	}
+	if mp.Latency != "" {
+		updated.Latency = utils.StringInputDefault(cmd, "query", "Latency Query", mp.Latency)
+	}
+	if mp.Throughput != "" {
+		updated.Throughput = utils.StringInputDefault(cmd, "query", "Throughput Query", mp.Throughput)
+	}
+	if mp.Saturation != "" {
+		updated.Saturation = utils.StringInputDefault(cmd, "query", "Saturation Query", mp.Saturation)
+	}
+	if mp.Durability != "" {
+		updated.Durability = utils.StringInputDefault(cmd, "query", "Durability Query", mp.Durability)
+	}
+	if mp.Correctness != "" {
+		updated.Correctness = utils.StringInputDefault(cmd, "query", "Correctness Query", mp.Correctness)
+	}
+	return updated
+}
+
+func sliUpdate() *cobra.Command {
+	update := &cobra.Command{
+		Use:   "update",
+		Short: "Update an SLI",
+		Long:  `Update the name, description, service and queries of an existing SLI`,
+		Run: func(cmd *cobra.Command, args []string) {
+			orgId := utils.IntInput(cmd, "org-id", "Org ID")
+			sliId := utils.IntInput(cmd, "sli-id", "SLI ID")
+			current, err := models.GetSli(&models.GetSliRequest{
+				OrgId: orgId,
+				Id:    sliId,
+			})
+			if err != nil {
+				log.Fatalf("unable to fetch SLI: \n%+v", err)
+			}
+			metricPath, err := current.Sli.DecodeMetricPath()
+			if err != nil {
+				log.Fatalf("%+v", err)
+			}
+
+			sliBody := *current.Sli
+			sliBody.Name = utils.StringInputDefault(cmd, "name", "Name", current.Sli.Name)
+			sliBody.Description = utils.StringInputDefault(cmd, "description", "Description", current.Sli.Description)
+			sliBody.ServiceId = utils.IntInputDefault(cmd, "service-id", "Service ID", current.Sli.ServiceId)
+			mp, err := json.Marshal(metricPathInputDefault(cmd, metricPath))
+			if err != nil {
+				log.Fatalf("error while marshaling metric path: %s", err)
+			}
+			sliBody.MetricPath = string(mp)
+
+			resp, err := models.UpdateSli(&models.UpdateSliRequest{
+				OrgId: orgId,
+				Id:    sliId,
+				Model: &sliBody,
+			})
+			if err != nil {
+				log.Fatalf("unable to update SLI: \n%+v", err)
+			}
+			render(cmd, resp.Sli, sliTable(resp.Sli))
+		},
+	}
+	addOrgIdFlag(update)
+	update.Flags().Int("sli-id", 0, "SLI to update")
+	update.Flags().String("name", "", "new SLI name")
+	update.Flags().String("description", "", "new SLI description")
+	update.Flags().Int("service-id", 0, "new service the SLI measures")
+	addQueryFlags(update)
+
+	return update
+}
+
+func sliDelete() *cobra.Command {
+	del := &cobra.Command{
+		Use:   "delete",
+		Short: "Delete an SLI",
+		Long:  `Delete an SLI and the raw data stored for it`,
+		Run: func(cmd *cobra.Command, args []string) {
+			orgId := utils.IntInput(cmd, "org-id", "Org ID")
+			sliId := utils.IntInput(cmd, "sli-id", "SLI ID")
+			if !utils.ConfirmInput(cmd, "yes", fmt.Sprintf("Delete SLI %d", sliId)) {
+				aborted()
+				return
+			}
+			if _, err := models.DeleteSli(&models.DeleteSliRequest{
+				OrgId: orgId,
+				Id:    sliId,
+			}); err != nil {
+				log.Fatalf("unable to delete SLI: \n%+v", err)
+			}
+			renderDeleted(cmd, "sli", sliId)
+		},
+	}
+	addOrgIdFlag(del)
+	del.Flags().Int("sli-id", 0, "SLI to delete")
+	addYesFlag(del)
+
+	return del
+}
+
+func sliList() *cobra.Command {
+	req := &models.ListSlisRequest{}
+	var all bool
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List SLIs",
+		Long:  `List SLIs page by page, optionally filtered by service and SLI type`,
+		Run: func(cmd *cobra.Command, args []string) {
+			req.OrgId = utils.IntInput(cmd, "org-id", "Org ID")
+			var slis []*models.SliBody
+			if all {
+				resp, err := models.ListAllSlis(req)
+				if err != nil {
+					log.Fatalf("unable to list SLIs: \n%+v", err)
+				}
+				slis = resp
+			} else {
+				resp, err := models.ListSlis(req)
+				if err != nil {
+					log.Fatalf("unable to list SLIs: \n%+v", err)
+				}
+				slis = resp.Slis
+			}
+			render(cmd, slis, sliTable(slis...))
+		},
+	}
+	addOrgIdFlag(list)
+	list.Flags().IntVar(&req.ServiceId, "service-id", 0, "only list SLIs of this service")
+	list.Flags().IntVar(&req.SliTypeId, "type-id", 0, "only list SLIs of this SLI type")
+	list.Flags().IntVar(&req.Page, "page", 1, "page to list")
+	list.Flags().IntVar(&req.PageSize, "page-size", models.DefaultPageSize, "SLIs per page")
+	list.Flags().BoolVar(&all, "all", false, "list every page")
+
+	return list
+}
+
+func sliTypes() *cobra.Command {
+	types := &cobra.Command{
+		Use:   "types",
+		Short: "List SLI types",
+		Long:  `List the SLI types Blameless supports`,
+		Run: func(cmd *cobra.Command, args []string) {
+			catalog, err := models.CachedSliTypes()
+			if err != nil {
+				log.Fatalf("unable to list SLI types: \n%+v", err)
+			}
+			t := output.NewTable("ID", "Name")
+			for _, st := range catalog {
+				t.AddRow(st.Id, st.Name)
+			}
+			render(cmd, catalog, t)
+		},
+	}
+
+	return types
+}
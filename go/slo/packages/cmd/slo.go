@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+	"github.com/blamelesshq/blameless-examples/slo/packages/output"
+	"github.com/blamelesshq/blameless-examples/slo/packages/utils"
+	"github.com/spf13/cobra"
+)
+
+func slo() *cobra.Command {
+	slo := &cobra.Command{
+		Use:   "slo",
+		Short: "SLO domain primary command",
+		Long:  `SLO and error budget policy commands begin here. `,
+	}
+
+	slo.AddCommand(sloCreate())
+	slo.AddCommand(sloGet())
+	slo.AddCommand(sloUpdate())
+	slo.AddCommand(sloDelete())
+	slo.AddCommand(sloList())
+	slo.AddCommand(policy())
+
+	return slo
+}
+
+func sloTable(slos ...*models.SloBody) *output.Table {
+	t := output.NewTable("Org ID", "ID", "Name", "Description", "SLI ID", "Objective %", "Window", "Threshold", "Policy IDs")
+	for _, s := range slos {
+		t.AddRow(s.OrgId,
+			s.Id,
+			s.Name,
+			s.Description,
+			s.SliId,
+			s.ObjectivePercentage,
+			s.Window.String(),
+			s.Threshold,
+			joinInts(s.ErrorBudgetPolicyIds),
+		)
+	}
+	return t
+}
+
+func joinInts(values []int) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = strconv.Itoa(v)
+	}
+	return strings.Join(s, ",")
+}
+
+// sliTypeName looks up the type of the SLI an SLO is built on, latency SLOs need a threshold
+func sliTypeName(orgId int, sliId int) string {
+	resp, err := models.GetSli(&models.GetSliRequest{
+		OrgId: orgId,
+		Id:    sliId,
+	})
+	if err != nil {
+		log.Fatalf("unable to fetch SLI: \n%+v", err)
+	}
+	st, err := resp.Sli.GetSliType()
+	if err != nil {
+		log.Fatalf("unable to get SLI type: \n%+v", err)
+	}
+	return st.SliType.Name
+}
+
+// sloInput reads the objective, window and threshold from flags or prompts, using current as defaults
+func sloInput(cmd *cobra.Command, current *models.SloBody, sliType string) *models.SloBody {
+	s := *current
+	s.Name = utils.StringInputDefault(cmd, "name", "Name", current.Name)
+	s.Description = utils.StringInputDefault(cmd, "description", "Description", current.Description)
+	s.ObjectivePercentage = utils.FloatInputDefault(cmd, "objective", "Objective Percentage", current.ObjectivePercentage)
+
+	window := &models.SloWindow{}
+	if current.Window != nil {
+		window.Type = current.Window.Type
+	}
+	window.Type = utils.SelectInput(cmd, "window-type", "Window Type", []string{models.WindowTypes.Rolling, models.WindowTypes.Calendar}, window.Type)
+	if window.Type == models.WindowTypes.Rolling {
+		days := models.MaxRollingDays
+		if current.Window != nil && current.Window.Days > 0 {
+			days = current.Window.Days
+		}
+		window.Days = utils.IntInputDefault(cmd, "window-days", "Window Days", days)
+	} else {
+		unit := ""
+		if current.Window != nil {
+			unit = current.Window.Unit
+		}
+		window.Unit = utils.SelectInput(cmd, "window-unit", "Calendar Unit", models.CalendarUnits, unit)
+	}
+	s.Window = window
+
+	s.Threshold = 0
+	if strings.EqualFold(sliType, models.Types.Latency) {
+		s.Threshold = utils.FloatInputDefault(cmd, "threshold", "Latency Threshold", current.Threshold)
+	}
+	return &s
+}
+
+func addSloFlags(c *cobra.Command) {
+	c.Flags().String("name", "", "SLO name")
+	c.Flags().String("description", "", "SLO description")
+	c.Flags().Float64("objective", 0, "target percentage of good events, e.g. 99.9")
+	c.Flags().String("window-type", "", "rolling or calendar")
+	c.Flags().Int("window-days", 0, "length of a rolling window in days")
+	c.Flags().String("window-unit", "", "week, month or quarter for a calendar window")
+	c.Flags().Float64("threshold", 0, "latency a good event stays under, latency SLIs only")
+}
+
+func formatFloat(f float64) string {
+	if f == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func sloCreate() *cobra.Command {
+	var policyIds []int
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "Create a new SLO",
+		Long:  `Create a new SLO on top of an existing SLI`,
+		Run: func(cmd *cobra.Command, args []string) {
+			orgId := utils.IntInput(cmd, "org-id", "Org ID")
+			sliId := utils.IntInput(cmd, "sli-id", "SLI ID")
+			sliType := sliTypeName(orgId, sliId)
+
+			sloBody := sloInput(cmd, &models.SloBody{SliId: sliId}, sliType)
+			sloBody.ErrorBudgetPolicyIds = policyIds
+			if err := sloBody.Validate(sliType); err != nil {
+				log.Fatalf("invalid SLO: %v", err)
+			}
+			resp, err := models.PostSlo(&models.PostSloRequest{
+				OrgId: orgId,
+				Model: sloBody,
+			})
+			if err != nil {
+				log.Fatalf("unable to create SLO: \n%+v", err)
+			}
+			render(cmd, resp.Slo, sloTable(resp.Slo))
+		},
+	}
+	addOrgIdFlag(create)
+	create.Flags().Int("sli-id", 0, "SLI the SLO is built on")
+	addSloFlags(create)
+	create.Flags().IntSliceVar(&policyIds, "policy-id", []int{}, "error budget policy to link, repeatable")
+
+	return create
+}
+
+func sloGet() *cobra.Command {
+	get := &cobra.Command{
+		Use:   "get",
+		Short: "get an SLO",
+		Long:  `get an SLO`,
+		Run: func(cmd *cobra.Command, args []string) {
+			resp, err := models.GetSlo(&models.GetSloRequest{
+				OrgId: utils.IntInput(cmd, "org-id", "Org ID"),
+				Id:    utils.IntInput(cmd, "slo-id", "SLO ID"),
+			})
+			if err != nil {
+				log.Fatalf("unable to complete request: \n%+v", err)
+			}
+			render(cmd, resp.Slo, sloTable(resp.Slo))
+		},
+	}
+	addOrgIdFlag(get)
+	get.Flags().Int("slo-id", 0, "SLO to get")
+
+	return get
+}
+
+func sloUpdate() *cobra.Command {
+	var policyIds []int
+	update := &cobra.Command{
+		Use:   "update",
+		Short: "Update an SLO",
+		Long:  `Update the objective, window, threshold and linked policies of an SLO`,
+		Run: func(cmd *cobra.Command, args []string) {
+			orgId := utils.IntInput(cmd, "org-id", "Org ID")
+			sloId := utils.IntInput(cmd, "slo-id", "SLO ID")
+			current, err := models.GetSlo(&models.GetSloRequest{
+				OrgId: orgId,
+				Id:    sloId,
+			})
+			if err != nil {
+				log.Fatalf("unable to fetch SLO: \n%+v", err)
+			}
+			sliType := sliTypeName(orgId, current.Slo.SliId)
+
+			sloBody := sloInput(cmd, current.Slo, sliType)
+			if cmd.Flags().Changed("policy-id") {
+				sloBody.ErrorBudgetPolicyIds = policyIds
+			}
+			if err := sloBody.Validate(sliType); err != nil {
+				log.Fatalf("invalid SLO: %v", err)
+			}
+			resp, err := models.UpdateSlo(&models.UpdateSloRequest{
+				OrgId: orgId,
+				Id:    sloId,
+				Model: sloBody,
+			})
+			if err != nil {
+				log.Fatalf("unable to update SLO: \n%+v", err)
+			}
+			render(cmd, resp.Slo, sloTable(resp.Slo))
+		},
+	}
+	addOrgIdFlag(update)
+	update.Flags().Int("slo-id", 0, "SLO to update")
+	addSloFlags(update)
+	update.Flags().IntSliceVar(&policyIds, "policy-id", []int{}, "replace the linked error budget policies, repeatable")
+
+	return update
+}
+
+func sloDelete() *cobra.Command {
+	del := &cobra.Command{
+		Use:   "delete",
+		Short: "Delete an SLO",
+		Long:  `Delete an SLO, the SLI it is built on is kept`,
+		Run: func(cmd *cobra.Command, args []string) {
+			orgId := utils.IntInput(cmd, "org-id", "Org ID")
+			sloId := utils.IntInput(cmd, "slo-id", "SLO ID")
+			if !utils.ConfirmInput(cmd, "yes", fmt.Sprintf("Delete SLO %d", sloId)) {
+				aborted()
+				return
+			}
+			if _, err := models.DeleteSlo(&models.DeleteSloRequest{
+				OrgId: orgId,
+				Id:    sloId,
+			}); err != nil {
+				log.Fatalf("unable to delete SLO: \n%+v", err)
+			}
+			renderDeleted(cmd, "slo", sloId)
+		},
+	}
+	addOrgIdFlag(del)
+	del.Flags().Int("slo-id", 0, "SLO to delete")
+	addYesFlag(del)
+
+	return del
+}
+
+func sloList() *cobra.Command {
+	req := &models.ListSlosRequest{}
+	var all bool
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List SLOs",
+		Long:  `List SLOs page by page, optionally only those built on one SLI`,
+		Run: func(cmd *cobra.Command, args []string) {
+			req.OrgId = utils.IntInput(cmd, "org-id", "Org ID")
+			var slos []*models.SloBody
+			if all {
+				resp, err := models.ListAllSlos(req)
+				if err != nil {
+					log.Fatalf("unable to list SLOs: \n%+v", err)
+				}
+				slos = resp
+			} else {
+				resp, err := models.ListSlos(req)
+				if err != nil {
+					log.Fatalf("unable to list SLOs: \n%+v", err)
+				}
+				slos = resp.Slos
+			}
+			render(cmd, slos, sloTable(slos...))
+		},
+	}
+	addOrgIdFlag(list)
+	list.Flags().IntVar(&req.SliId, "sli-id", 0, "only list SLOs built on this SLI")
+	list.Flags().IntVar(&req.Page, "page", 1, "page to list")
+	list.Flags().IntVar(&req.PageSize, "page-size", models.DefaultPageSize, "SLOs per page")
+	list.Flags().BoolVar(&all, "all", false, "list every page")
+
+	return list
+}
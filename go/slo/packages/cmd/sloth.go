@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/config"
+	"github.com/blamelesshq/blameless-examples/slo/packages/ingest"
+	"github.com/blamelesshq/blameless-examples/slo/packages/manifest"
+	"github.com/blamelesshq/blameless-examples/slo/packages/sloth"
+	"github.com/blamelesshq/blameless-examples/slo/packages/utils"
+	"github.com/spf13/cobra"
+)
+
+func slothCmd() *cobra.Command {
+	s := &cobra.Command{
+		Use:   "sloth",
+		Short: "Sloth domain primary command",
+		Long:  `Bootstrap Blameless SLIs from Sloth prometheus/v1 SLO specs`,
+	}
+
+	s.AddCommand(slothImport())
+
+	return s
+}
+
+func slothImport() *cobra.Command {
+	var file string
+	var prune bool
+	var dryRun bool
+	var mappingFile string
+	var window string
+	var backfill bool
+	imp := &cobra.Command{
+		Use:   "import",
+		Short: "Import Sloth SLO specs",
+		Long: `Create or update an availability SLI, and an SLO with the same objective, for every SLO in Sloth specs.
+The good query is the total query minus the error query, unless the mapping file gives explicit queries.
+The mapping file resolves Sloth services to Blameless service IDs:
+
+  services:
+    checkout: 12
+  queries: # optional, keyed by <service>/<slo name>
+    checkout/requests-availability:
+      goodRequest: sum(rate(http_requests_total{job="checkout",code!~"5.."}[{{.window}}]))
+      validRequest: sum(rate(http_requests_total{job="checkout"}[{{.window}}]))`,
+		Run: func(cmd *cobra.Command, args []string) {
+			specs, err := sloth.LoadSpecs(file)
+			if err != nil {
+				log.Fatalf("unable to read Sloth specs: \n%+v", err)
+			}
+			mapping, err := sloth.LoadMapping(mappingFile)
+			if err != nil {
+				log.Fatalf("unable to read mapping file: \n%+v", err)
+			}
+			m, err := sloth.Import(specs, mapping, window)
+			if err != nil {
+				log.Fatalf("unable to translate Sloth specs: %v", err)
+			}
+			orgId := utils.IntInput(cmd, "org-id", "Org ID")
+			plan := applyManifest(cmd, m, orgId, prune, dryRun)
+			if plan == nil || dryRun || !backfill {
+				return
+			}
+			backfillCreated(cmd, orgId, plan)
+		},
+	}
+	addManifestFlags(imp, &file, &prune)
+	imp.Flags().Lookup("filename").Usage = "Sloth spec file or a directory of them"
+	imp.Flags().StringVar(&mappingFile, "mapping", "", "YAML file mapping Sloth services to Blameless service IDs")
+	imp.MarkFlagRequired("mapping")
+	imp.Flags().StringVar(&window, "window", "", "range substituted for {{.window}} in Sloth queries, defaults to ingest.step")
+	defaultFromConfig(imp, "window", func(env *config.Config) string { return fmt.Sprintf("%ds", env.Ingest.Step) })
+	imp.Flags().BoolVar(&dryRun, "dry-run", false, "print the planned changes without making them")
+	imp.Flags().BoolVar(&backfill, "backfill", false, "backfill ingest.backfill days of raw data for every SLI the import creates")
+	addYesFlag(imp)
+
+	return imp
+}
+
+// backfillCreated backfills the SLIs a plan created over the last ingest.backfill days,
+// the way sli ingest backfill does
+func backfillCreated(cmd *cobra.Command, orgId int, plan *manifest.Plan) {
+	ids := []int{}
+	for _, c := range plan.Changes {
+		if c.Kind == manifest.Kinds.Sli && c.Action == manifest.Create {
+			ids = append(ids, c.Id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	env := config.Environment().Ingest
+	now := time.Now()
+	state, err := ingest.NewBackfillState(orgId, ids, now.AddDate(0, 0, -env.Backfill), now, time.Duration(env.Step)*time.Second)
+	if err != nil {
+		log.Fatalf("unable to plan backfill: \n%+v", err)
+	}
+	runBackfill(cmd, state, "", defaultBackfillConcurrency)
+}
@@ -10,26 +10,87 @@ import (
 var configOncer sync.Once
 var config *Config
 
+type RateLimit struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
 type Prometheus struct {
-	Host string
-	Port int
+	Host        string
+	Port        int
+	Username    string
+	Password    Secret
+	BearerToken Secret
+	RateLimit   RateLimit
 }
 
 type Ingest struct {
-	Backfill int
-	Period   int
-	Step     int
+	Backfill    int
+	Period      int
+	Step        int
+	GapLookback int
+}
+
+type Batch struct {
+	MaxPoints   int
+	MaxBytes    int
+	Concurrency int
 }
 
 type Blameless struct {
 	Host      string
 	Port      int
-	AuthToken string
+	AuthToken Secret
 	OrgId     int
+	RateLimit RateLimit
+	Batch     Batch
+	Gzip      bool
+}
+
+type Retry struct {
+	Count           int
+	WaitTime        int
+	MaxWaitTime     int
+	StatusCodes     []int
+	TransportErrors bool
+}
+
+type CircuitBreaker struct {
+	FailureRate      float64
+	MinRequests      int
+	Window           int
+	OpenTimeout      int
+	HalfOpenRequests int
 }
 
 type Http struct {
 	RequestTimeout int
+	Retry          Retry
+	CircuitBreaker CircuitBreaker
+}
+
+type Log struct {
+	Level  string
+	Format string
+}
+
+type Outbox struct {
+	Dir           string
+	FlushInterval int
+}
+
+type Cache struct {
+	TTL int
+}
+
+type Budget struct {
+	BurnWindows      []string
+	ForecastLookback string
+	ForecastAlpha    float64
+}
+
+type Metrics struct {
+	Addr string
 }
 
 type Config struct {
@@ -37,17 +98,55 @@ type Config struct {
 	Ingest     Ingest
 	Blameless  Blameless
 	Http       Http
+	Log        Log
+	Outbox     Outbox
+	Cache      Cache
+	Budget     Budget
+	Metrics    Metrics
 }
 
 type ConfigClient interface {
 	Environment() *Config
 }
 
+func rateLimit(key string) RateLimit {
+	return RateLimit{
+		RequestsPerSecond: viper.GetFloat64(key + ".requestsPerSecond"),
+		Burst:             viper.GetInt(key + ".burst"),
+	}
+}
+
+// setDefaults keeps older config files working as new settings are introduced
+func setDefaults() {
+	viper.SetDefault("http.retry.count", 3)
+	viper.SetDefault("http.retry.waitTime", 5)
+	viper.SetDefault("http.retry.maxWaitTime", 60)
+	viper.SetDefault("http.retry.statusCodes", []int{429, 502, 503, 504})
+	viper.SetDefault("http.retry.transportErrors", true)
+	viper.SetDefault("http.circuitBreaker.failureRate", 0.5)
+	viper.SetDefault("http.circuitBreaker.minRequests", 5)
+	viper.SetDefault("http.circuitBreaker.window", 60)
+	viper.SetDefault("http.circuitBreaker.openTimeout", 30)
+	viper.SetDefault("http.circuitBreaker.halfOpenRequests", 1)
+	viper.SetDefault("blameless.batch.maxPoints", 1000)
+	viper.SetDefault("blameless.batch.maxBytes", 1<<20)
+	viper.SetDefault("blameless.batch.concurrency", 2)
+	viper.SetDefault("outbox.dir", ".outbox")
+	viper.SetDefault("outbox.flushInterval", 60)
+	viper.SetDefault("cache.ttl", 300)
+	viper.SetDefault("ingest.gapLookback", 28)
+	viper.SetDefault("budget.burnWindows", []string{"1h", "6h", "1d", "3d"})
+	viper.SetDefault("budget.forecastLookback", "3d")
+	viper.SetDefault("budget.forecastAlpha", 0.3)
+	viper.SetDefault("metrics.addr", "")
+}
+
 func Environment() *Config {
 	configOncer.Do(func() {
 		viper.SetConfigName("config")
 		viper.SetConfigType("yaml")
 		viper.AddConfigPath(".")
+		setDefaults()
 
 		if err := viper.ReadInConfig(); err != nil {
 			log.Fatal("Unable to read in config")
@@ -55,22 +154,67 @@ func Environment() *Config {
 
 		config = &Config{
 			Prometheus: Prometheus{
-				Host: viper.GetString("prometheus.host"),
-				Port: viper.GetInt("prometheus.port"),
+				Host:        viper.GetString("prometheus.host"),
+				Port:        viper.GetInt("prometheus.port"),
+				Username:    viper.GetString("prometheus.username"),
+				Password:    Secret(viper.GetString("prometheus.password")),
+				BearerToken: Secret(viper.GetString("prometheus.bearerToken")),
+				RateLimit:   rateLimit("prometheus.rateLimit"),
 			},
 			Ingest: Ingest{
-				Backfill: viper.GetInt("ingest.backfill"),
-				Period:   viper.GetInt("ingest.period"),
-				Step:     viper.GetInt("ingest.step"),
+				Backfill:    viper.GetInt("ingest.backfill"),
+				Period:      viper.GetInt("ingest.period"),
+				Step:        viper.GetInt("ingest.step"),
+				GapLookback: viper.GetInt("ingest.gapLookback"),
 			},
 			Blameless: Blameless{
 				Host:      viper.GetString("blameless.host"),
 				Port:      viper.GetInt("blameless.port"),
-				AuthToken: viper.GetString("blameless.authToken"),
+				AuthToken: Secret(viper.GetString("blameless.authToken")),
 				OrgId:     viper.GetInt("blameless.orgId"),
+				RateLimit: rateLimit("blameless.rateLimit"),
+				Batch: Batch{
+					MaxPoints:   viper.GetInt("blameless.batch.maxPoints"),
+					MaxBytes:    viper.GetInt("blameless.batch.maxBytes"),
+					Concurrency: viper.GetInt("blameless.batch.concurrency"),
+				},
+				Gzip: viper.GetBool("blameless.gzip"),
 			},
 			Http: Http{
 				RequestTimeout: viper.GetInt("http.requestTimeout"),
+				Retry: Retry{
+					Count:           viper.GetInt("http.retry.count"),
+					WaitTime:        viper.GetInt("http.retry.waitTime"),
+					MaxWaitTime:     viper.GetInt("http.retry.maxWaitTime"),
+					StatusCodes:     viper.GetIntSlice("http.retry.statusCodes"),
+					TransportErrors: viper.GetBool("http.retry.transportErrors"),
+				},
+				CircuitBreaker: CircuitBreaker{
+					FailureRate:      viper.GetFloat64("http.circuitBreaker.failureRate"),
+					MinRequests:      viper.GetInt("http.circuitBreaker.minRequests"),
+					Window:           viper.GetInt("http.circuitBreaker.window"),
+					OpenTimeout:      viper.GetInt("http.circuitBreaker.openTimeout"),
+					HalfOpenRequests: viper.GetInt("http.circuitBreaker.halfOpenRequests"),
+				},
+			},
+			Log: Log{
+				Level:  viper.GetString("log.level"),
+				Format: viper.GetString("log.format"),
+			},
+			Outbox: Outbox{
+				Dir:           viper.GetString("outbox.dir"),
+				FlushInterval: viper.GetInt("outbox.flushInterval"),
+			},
+			Cache: Cache{
+				TTL: viper.GetInt("cache.ttl"),
+			},
+			Budget: Budget{
+				BurnWindows:      viper.GetStringSlice("budget.burnWindows"),
+				ForecastLookback: viper.GetString("budget.forecastLookback"),
+				ForecastAlpha:    viper.GetFloat64("budget.forecastAlpha"),
+			},
+			Metrics: Metrics{
+				Addr: viper.GetString("metrics.addr"),
 			},
 		}
 	})
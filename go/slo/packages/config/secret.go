@@ -0,0 +1,89 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+const envPrefix = "env:"
+const filePrefix = "file:"
+const execPrefix = "exec:"
+
+// Credential helpers should answer quickly, a hung helper should not hang the CLI
+const execTimeout = 30 * time.Second
+
+// Secret holds a credential reference, see ResolveSecret. It is only resolved where the
+// value is sent, so commands that never call the backend do not need it, and it redacts
+// itself when formatted or marshaled so it can not leak through log lines or printed config.
+type Secret string
+
+// Resolve returns the plain text credential, only call this where the value is sent
+func (s Secret) Resolve() (string, error) {
+	return ResolveSecret(string(s))
+}
+
+// IsSet reports whether a credential was configured
+func (s Secret) IsSet() bool {
+	return len(strings.TrimSpace(string(s))) > 0
+}
+
+func (s Secret) String() string {
+	if !s.IsSet() {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return fmt.Sprintf("config.Secret(%q)", s.String())
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+// ResolveSecret resolves a secret reference of the form env:VAR, file:/path
+// or exec:command. Anything else is treated as a literal value.
+func ResolveSecret(ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	switch {
+	case strings.HasPrefix(ref, envPrefix):
+		name := strings.TrimPrefix(ref, envPrefix)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return strings.TrimSpace(value), nil
+	case strings.HasPrefix(ref, filePrefix):
+		path := strings.TrimPrefix(ref, filePrefix)
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("unable to read secret file %s: %v", path, err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	case strings.HasPrefix(ref, execPrefix):
+		command := strings.TrimPrefix(ref, execPrefix)
+		ctx, cancel := context.WithTimeout(context.Background(), execTimeout)
+		defer cancel()
+		// The helper output is the secret, so stderr is passed through but never captured into errors
+		c := exec.CommandContext(ctx, "sh", "-c", command)
+		c.Stderr = os.Stderr
+		out, err := c.Output()
+		if err != nil {
+			return "", fmt.Errorf("credential helper %q failed: %v", command, err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+	return ref, nil
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveSecret(t *testing.T) {
+	t.Setenv("SLO_TEST_TOKEN", " from-env \n")
+	dir := t.TempDir()
+	file := filepath.Join(dir, "token")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{name: "literal", ref: "plain-token", want: "plain-token"},
+		{name: "empty", ref: "", want: ""},
+		{name: "env", ref: "env:SLO_TEST_TOKEN", want: "from-env"},
+		{name: "unset env", ref: "env:SLO_TEST_UNSET_TOKEN", wantErr: true},
+		{name: "file", ref: "file:" + file, want: "from-file"},
+		{name: "missing file", ref: "file:" + filepath.Join(dir, "missing"), wantErr: true},
+		{name: "exec", ref: "exec:echo from-exec", want: "from-exec"},
+		{name: "failing exec", ref: "exec:exit 3", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveSecret(tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveSecret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveSecret() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecretIsResolvedWhenUsed(t *testing.T) {
+	s := Secret("env:SLO_TEST_LATE_TOKEN")
+	if _, err := s.Resolve(); err == nil {
+		t.Fatal("Resolve() error = nil before the variable is set")
+	}
+	t.Setenv("SLO_TEST_LATE_TOKEN", "late")
+	if got, err := s.Resolve(); err != nil || got != "late" {
+		t.Errorf("Resolve() = %q, %v, want late", got, err)
+	}
+}
+
+func TestSecretRedaction(t *testing.T) {
+	s := Secret("literal-token")
+	var logged bytes.Buffer
+	slog.New(slog.NewTextHandler(&logged, nil)).Info("config", "token", s)
+	marshaled, err := json.Marshal(struct{ Token Secret }{s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		out  string
+	}{
+		{"print", fmt.Sprint(s)},
+		{"verb v", fmt.Sprintf("%v", s)},
+		{"verb +v", fmt.Sprintf("%+v", struct{ Token Secret }{s})},
+		{"verb #v", fmt.Sprintf("%#v", s)},
+		{"json", string(marshaled)},
+		{"slog", logged.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if strings.Contains(tt.out, "literal-token") || !strings.Contains(tt.out, redacted) {
+				t.Errorf("%s = %q, want the secret redacted", tt.name, tt.out)
+			}
+		})
+	}
+	if got := Secret("").String(); got != "" {
+		t.Errorf("unset Secret = %q, want empty", got)
+	}
+}
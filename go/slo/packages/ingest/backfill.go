@@ -0,0 +1,239 @@
+package ingest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/clients"
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+)
+
+// backfillWindowSteps is how many steps a range backfill queries at once, an hour at the default step
+const backfillWindowSteps = 60
+
+// BackfillState records a range backfill, a run that stops is resumed from it and
+// only queries the windows that were not posted
+type BackfillState struct {
+	OrgId  int             `json:"orgId"`
+	SliIds []int           `json:"sliIds"`
+	From   time.Time       `json:"from"`
+	To     time.Time       `json:"to"`
+	Step   int             `json:"step"` // Seconds
+	Done   map[int][]int64 `json:"done"` // Unix start of the windows posted, by SLI
+
+	mu   sync.Mutex
+	done map[int]map[int64]bool
+}
+
+// NewBackfillState plans a backfill of [from, to) at step, from is aligned to the step
+func NewBackfillState(orgId int, sliIds []int, from time.Time, to time.Time, step time.Duration) (*BackfillState, error) {
+	if step < time.Second {
+		return nil, fmt.Errorf("step must be at least 1s, got %s", step)
+	}
+	s := &BackfillState{
+		OrgId:  orgId,
+		SliIds: sliIds,
+		From:   from.Truncate(step),
+		To:     to,
+		Step:   int(step.Seconds()),
+		Done:   map[int][]int64{},
+	}
+	s.index()
+	return s, nil
+}
+
+func LoadBackfillState(path string) (*BackfillState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &BackfillState{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("unable to parse backfill state %s: %w", path, err)
+	}
+	if s.Done == nil {
+		s.Done = map[int][]int64{}
+	}
+	s.index()
+	return s, nil
+}
+
+// Save writes the state through a temporary file so an interrupted save keeps the previous state
+func (s *BackfillState) Save(path string) error {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *BackfillState) index() {
+	s.done = map[int]map[int64]bool{}
+	for sliId, starts := range s.Done {
+		s.done[sliId] = map[int64]bool{}
+		for _, start := range starts {
+			s.done[sliId][start] = true
+		}
+	}
+}
+
+func (s *BackfillState) step() time.Duration {
+	return time.Duration(s.Step) * time.Second
+}
+
+// Windows lists every window of the range
+func (s *BackfillState) Windows() []Window {
+	return Windows(s.From, s.To, backfillWindowSteps*s.step())
+}
+
+// Pending lists the windows of an SLI that were not posted yet
+func (s *BackfillState) Pending(sliId int) []Window {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := []Window{}
+	for _, w := range s.Windows() {
+		if !s.done[sliId][w.From.Unix()] {
+			pending = append(pending, w)
+		}
+	}
+	return pending
+}
+
+func (s *BackfillState) markDone(sliId int, w Window) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done[sliId] == nil {
+		s.done[sliId] = map[int64]bool{}
+	}
+	s.done[sliId][w.From.Unix()] = true
+	s.Done[sliId] = append(s.Done[sliId], w.From.Unix())
+	sort.Slice(s.Done[sliId], func(i, j int) bool { return s.Done[sliId][i] < s.Done[sliId][j] })
+}
+
+// BackfillResult is the outcome of one window of one SLI
+type BackfillResult struct {
+	SliId  int
+	Window Window
+	Points int
+	Err    error
+}
+
+type backfillTask struct {
+	sliId  int
+	window Window
+	fetch  fetcher
+	post   sink
+}
+
+// ErrInterrupted is returned by BackfillRange when it was interrupted before every window started
+var ErrInterrupted = errors.New("backfill interrupted")
+
+// BackfillRange backfills the pending windows of every SLI with concurrency workers,
+// reporting each window to progress as it completes. Windows are marked done on the
+// state, the first failure stops new windows from starting and is returned once the
+// running ones finish. Closing interrupt stops new windows the same way. Batches kept
+// in the outbox count as posted.
+func BackfillRange(p *clients.PrometheusClient, slis []*models.SliBody, state *BackfillState, concurrency int, interrupt <-chan struct{}, progress func(*BackfillResult)) error {
+	p = p.WithStep(state.step())
+
+	tasks := []*backfillTask{}
+	for _, sli := range slis {
+		fetch, sliType, err := metricPathFetcher(p, sli)
+		if err != nil {
+			return fmt.Errorf("sli %d: %w", sli.Id, err)
+		}
+		post := deliverer(sliType)
+		for _, w := range state.Pending(sli.Id) {
+			tasks = append(tasks, &backfillTask{sliId: sli.Id, window: w, fetch: fetch, post: post})
+		}
+	}
+	return runBackfillTasks(tasks, state, concurrency, interrupt, progress)
+}
+
+func runBackfillTasks(tasks []*backfillTask, state *BackfillState, concurrency int, interrupt <-chan struct{}, progress func(*BackfillResult)) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	queue := make(chan *backfillTask)
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	var firstErr error
+	interrupted := false
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range queue {
+				// A task queued as the run was interrupted is left pending
+				select {
+				case <-interrupt:
+					mu.Lock()
+					interrupted = true
+					mu.Unlock()
+					continue
+				default:
+				}
+				result := &BackfillResult{SliId: t.sliId, Window: t.window}
+				result.Err = backfillWindow(t.fetch, func(from time.Time, to time.Time, rawDatas []models.SliRawDataBody, skipped int) error {
+					result.Points = len(rawDatas)
+					return t.post(from, to, rawDatas, skipped)
+				}, t.window)
+				if result.Err == nil {
+					state.markDone(t.sliId, t.window)
+				} else {
+					stopOnce.Do(func() {
+						firstErr = fmt.Errorf("sli %d window %s: %w", t.sliId, t.window.From.UTC().Format(time.RFC3339), result.Err)
+						close(stop)
+					})
+				}
+				mu.Lock()
+				progress(result)
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, t := range tasks {
+		select {
+		case queue <- t:
+		case <-stop:
+			break feed
+		case <-interrupt:
+			mu.Lock()
+			interrupted = true
+			mu.Unlock()
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+	if firstErr == nil && interrupted {
+		return ErrInterrupted
+	}
+	return firstErr
+}
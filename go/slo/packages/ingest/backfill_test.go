@@ -0,0 +1,238 @@
+package ingest
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+)
+
+func window(from int64, to int64) Window {
+	return Window{From: time.Unix(from, 0), To: time.Unix(to, 0)}
+}
+
+func TestWindows(t *testing.T) {
+	tests := []struct {
+		name string
+		from int64
+		to   int64
+		size time.Duration
+		want []Window
+	}{
+		{
+			name: "even split",
+			from: 0,
+			to:   7200,
+			size: time.Hour,
+			want: []Window{window(0, 3600), window(3600, 7200)},
+		},
+		{
+			name: "shorter last window",
+			from: 0,
+			to:   5400,
+			size: time.Hour,
+			want: []Window{window(0, 3600), window(3600, 5400)},
+		},
+		{
+			name: "range shorter than a window",
+			from: 60,
+			to:   120,
+			size: time.Hour,
+			want: []Window{window(60, 120)},
+		},
+		{
+			name: "empty range",
+			from: 3600,
+			to:   3600,
+			size: time.Hour,
+			want: []Window{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Windows(time.Unix(tt.from, 0), time.Unix(tt.to, 0), tt.size)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Windows() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewBackfillState(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    int64
+		to      int64
+		step    time.Duration
+		wantErr bool
+		windows []Window
+	}{
+		{
+			name:    "windows are an hour of steps",
+			from:    0,
+			to:      9000,
+			step:    time.Minute,
+			windows: []Window{window(0, 3600), window(3600, 7200), window(7200, 9000)},
+		},
+		{
+			name:    "from is aligned to the step",
+			from:    90,
+			to:      3600,
+			step:    time.Minute,
+			windows: []Window{window(60, 3600)},
+		},
+		{
+			name:    "windows scale with the step",
+			from:    0,
+			to:      7200,
+			step:    2 * time.Minute,
+			windows: []Window{window(0, 7200)},
+		},
+		{
+			name:    "step under a second",
+			from:    0,
+			to:      3600,
+			step:    time.Millisecond,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewBackfillState(1, []int{3}, time.Unix(tt.from, 0), time.Unix(tt.to, 0), tt.step)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewBackfillState() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := s.Windows(); !reflect.DeepEqual(got, tt.windows) {
+				t.Errorf("Windows() = %+v, want %+v", got, tt.windows)
+			}
+			if got := s.Pending(3); !reflect.DeepEqual(got, tt.windows) {
+				t.Errorf("Pending() = %+v, want every window %+v", got, tt.windows)
+			}
+		})
+	}
+}
+
+func TestBackfillStateResume(t *testing.T) {
+	s, err := NewBackfillState(1, []int{3, 4}, time.Unix(0, 0), time.Unix(3*3600, 0), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.markDone(3, window(7200, 10800))
+	s.markDone(3, window(0, 3600))
+
+	path := filepath.Join(t.TempDir(), "backfill.json")
+	if err := s.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := LoadBackfillState(path)
+	if err != nil {
+		t.Fatalf("LoadBackfillState() error = %v", err)
+	}
+
+	if want := []int64{0, 7200}; !reflect.DeepEqual(loaded.Done[3], want) {
+		t.Errorf("Done[3] = %v, want %v", loaded.Done[3], want)
+	}
+	if loaded.OrgId != 1 || !reflect.DeepEqual(loaded.SliIds, []int{3, 4}) || loaded.Step != 60 {
+		t.Errorf("loaded state = %+v", loaded)
+	}
+	tests := []struct {
+		sliId int
+		want  []Window
+	}{
+		{sliId: 3, want: []Window{window(3600, 7200)}},
+		{sliId: 4, want: []Window{window(0, 3600), window(3600, 7200), window(7200, 10800)}},
+	}
+	for _, tt := range tests {
+		got := loaded.Pending(tt.sliId)
+		if len(got) != len(tt.want) {
+			t.Fatalf("Pending(%d) = %+v, want %+v", tt.sliId, got, tt.want)
+		}
+		for i := range got {
+			if !got[i].From.Equal(tt.want[i].From) || !got[i].To.Equal(tt.want[i].To) {
+				t.Errorf("Pending(%d)[%d] = %+v, want %+v", tt.sliId, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestBackfillWindowDropsTheNextWindowStart(t *testing.T) {
+	w := window(0, 180)
+	fetch := func(from time.Time, to time.Time) ([]models.SliRawDataBody, int, error) {
+		data := []models.SliRawDataBody{}
+		// Prometheus range queries include both ends
+		for t := from.Unix(); t <= to.Unix(); t += 60 {
+			data = append(data, models.SliRawDataBody{SliId: 3, Start: int(t), End: int(t) + 60, Latency: 1})
+		}
+		return data, 0, nil
+	}
+	starts := []int{}
+	post := func(from time.Time, to time.Time, rawDatas []models.SliRawDataBody, skipped int) error {
+		for _, d := range rawDatas {
+			starts = append(starts, d.Start)
+		}
+		return nil
+	}
+	if err := backfillWindow(fetch, post, w); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{0, 60, 120}; !reflect.DeepEqual(starts, want) {
+		t.Errorf("posted starts = %v, want %v", starts, want)
+	}
+}
+
+func TestRunBackfillTasksInterrupt(t *testing.T) {
+	tests := []struct {
+		name string
+		// interruptAt closes interrupt as the window at this index is posted, -1 before the run
+		interruptAt int
+		done        int
+		wantErr     error
+	}{
+		{name: "not interrupted", interruptAt: 3, done: 3},
+		{name: "interrupted before the run", interruptAt: -1, wantErr: ErrInterrupted},
+		{name: "running windows finish", interruptAt: 0, done: 1, wantErr: ErrInterrupted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := NewBackfillState(1, []int{3}, time.Unix(0, 0), time.Unix(3*3600, 0), time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			interrupt := make(chan struct{})
+			if tt.interruptAt < 0 {
+				close(interrupt)
+			}
+			fetch := func(from time.Time, to time.Time) ([]models.SliRawDataBody, int, error) {
+				return []models.SliRawDataBody{}, 0, nil
+			}
+			posted := 0
+			post := func(from time.Time, to time.Time, rawDatas []models.SliRawDataBody, skipped int) error {
+				if posted == tt.interruptAt {
+					close(interrupt)
+				}
+				posted++
+				return nil
+			}
+			tasks := []*backfillTask{}
+			for _, w := range state.Pending(3) {
+				tasks = append(tasks, &backfillTask{sliId: 3, window: w, fetch: fetch, post: post})
+			}
+			reported := 0
+			err = runBackfillTasks(tasks, state, 1, interrupt, func(*BackfillResult) { reported++ })
+			if err != tt.wantErr {
+				t.Fatalf("runBackfillTasks() error = %v, want %v", err, tt.wantErr)
+			}
+			if reported != tt.done || len(state.Done[3]) != tt.done {
+				t.Errorf("reported %d windows and %d done, want %d", reported, len(state.Done[3]), tt.done)
+			}
+			if pending := len(state.Pending(3)); pending != 3-tt.done {
+				t.Errorf("Pending() = %d windows, want %d", pending, 3-tt.done)
+			}
+		})
+	}
+}
@@ -1,31 +1,37 @@
 package ingest
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/blamelesshq/blameless-examples/slo/packages/clients"
 	"github.com/blamelesshq/blameless-examples/slo/packages/config"
 	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+	"github.com/blamelesshq/blameless-examples/slo/packages/outbox"
 )
 
-type Ingest interface {
-	Backfill(p *clients.PrometheusClient, sliType string, sli *models.SliBody) error
-	Regular(p *clients.PrometheusClient, sliType string, sli *models.SliBody) (*models.PostManyResponse, error)
-}
-
-func buildModel(id int, tuples []clients.Values, sliType *models.SliTypeBody) ([]models.SliRawDataBody, error) {
-	rawDatas := make([]models.SliRawDataBody, len(tuples))
-	for i, t := range tuples {
+// buildModel turns samples into raw data, it also returns how many samples had no value
+func buildModel(id int, tuples []clients.Values, sliType *models.SliTypeBody, step int) ([]models.SliRawDataBody, int, error) {
+	rawDatas := make([]models.SliRawDataBody, 0, len(tuples))
+	skipped := 0
+	for _, t := range tuples {
 		model := models.SliRawDataBody{
 			SliId: id,
 			Start: t.Time,
-			End:   t.Time * config.Environment().Ingest.Step,
+			End:   t.Time + step,
 		}
-		value, err := strconv.Atoi(t.Value)
+		value, ok, err := sampleValue(t.Value)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
+		}
+		if !ok {
+			skipped++
+			continue
 		}
 
 		switch name := sliType.Name; name {
@@ -40,62 +46,265 @@ func buildModel(id int, tuples []clients.Values, sliType *models.SliTypeBody) ([
 		case models.Types.Correctness:
 			model.Correctness = value
 		}
-		rawDatas[i] = model
+		rawDatas = append(rawDatas, model)
 	}
-	return rawDatas, nil
+	return rawDatas, skipped, nil
 }
 
-// This is expensive to do in a linear programmatic fashion, you should use a distribute queue system for this
-func Backfill(p *clients.PrometheusClient, query string, sli *models.SliBody) error {
-	bClient := clients.NewBlamelessClient()
-	resp, err := sli.GetSliType()
+// sampleValue rounds a Prometheus sample to the integer raw data holds, NaN and
+// infinite samples have no value and are skipped
+func sampleValue(s string) (int, bool, error) {
+	f, err := strconv.ParseFloat(s, 64)
 	if err != nil {
-		return err
+		return 0, false, err
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false, nil
 	}
+	return int(math.Round(f)), true, nil
+}
 
-	start := time.Now().AddDate(0, 0, -28).Truncate(24 * time.Hour)
-	var now time.Time
-	// We support 28 day rolling windows so at max you should only backfill 56 days
-	for d := 1; d <= config.Environment().Ingest.Backfill; d++ {
-		now = start
-		for h := 1; h <= 24; h++ {
-			from := now.Add(time.Hour * time.Duration(h-1))
-			to := from.Add(time.Hour * time.Duration(h))
-			log.Printf("BACKFILLING SLI (ID): %d | QUERY %s | FROM: %s | TO: %s", sli.Id, query, from, to)
-			tuples, err := p.QueryRange(query, from, to)
-			if err != nil {
-				return err
-			}
-			rawDatas, err := buildModel(sli.Id, tuples, resp.SliType)
-			if err != nil {
-				return err
-			}
-			models.PostMany(bClient, rawDatas)
+// buildAvailabilityModel merges good and valid request samples by timestamp, a
+// timestamp needs both to be posted and is skipped otherwise
+func buildAvailabilityModel(id int, good []clients.Values, valid []clients.Values, step int) ([]models.SliRawDataBody, int, error) {
+	goodByTime := map[int]int{}
+	for _, t := range good {
+		value, ok, err := sampleValue(t.Value)
+		if err != nil {
+			return nil, 0, err
+		}
+		if ok {
+			goodByTime[t.Time] = value
 		}
-		now = now.AddDate(0, 0, 1).Truncate(24 * time.Hour)
 	}
+	rawDatas := make([]models.SliRawDataBody, 0, len(valid))
+	skipped := 0
+	for _, t := range valid {
+		value, ok, err := sampleValue(t.Value)
+		if err != nil {
+			return nil, 0, err
+		}
+		goodValue, hasGood := goodByTime[t.Time]
+		if !ok || !hasGood {
+			skipped++
+			continue
+		}
+		rawDatas = append(rawDatas, models.SliRawDataBody{
+			SliId:        id,
+			Start:        t.Time,
+			End:          t.Time + step,
+			GoodRequest:  goodValue,
+			ValidRequest: value,
+		})
+	}
+	return rawDatas, skipped, nil
+}
 
-	return nil
+// startingBefore drops the raw data starting at or after end. Prometheus range queries
+// include their end, which is also the start of the next range.
+func startingBefore(rawDatas []models.SliRawDataBody, end time.Time) []models.SliRawDataBody {
+	kept := rawDatas[:0]
+	for _, d := range rawDatas {
+		if int64(d.Start) < end.Unix() {
+			kept = append(kept, d)
+		}
+	}
+	return kept
 }
 
-func Regular(p *clients.PrometheusClient, query string, sli *models.SliBody) (*models.PostManyResponse, error) {
-	now := time.Now()
-	start := now.Add(time.Duration(-config.Environment().Ingest.Period/60) * time.Minute)
-	tuples, err := p.QueryRange(query, start, now)
+// queryRawData runs the queries on the SLI's metric path between from and to
+func queryRawData(p *clients.PrometheusClient, sli *models.SliBody, sliType *models.SliTypeBody, mp *models.MetricPath, from time.Time, to time.Time) ([]models.SliRawDataBody, int, error) {
+	if sliType.Name == models.Types.Availability {
+		if mp.Availability == nil {
+			return nil, 0, fmt.Errorf("availability sli %d has no good and valid queries", sli.Id)
+		}
+		good, err := p.QueryRange(mp.Availability.GoodRequest, from, to)
+		if err != nil {
+			return nil, 0, err
+		}
+		valid, err := p.QueryRange(mp.Availability.ValidRequest, from, to)
+		if err != nil {
+			return nil, 0, err
+		}
+		return buildAvailabilityModel(sli.Id, good, valid, stepSeconds(p))
+	}
+
+	queries := map[string]string{
+		models.Types.Latency:     mp.Latency,
+		models.Types.Throughput:  mp.Throughput,
+		models.Types.Saturation:  mp.Saturation,
+		models.Types.Correctness: mp.Correctness,
+		models.Types.Durability:  mp.Durability,
+	}
+	query := queries[sliType.Name]
+	if query == "" {
+		return nil, 0, fmt.Errorf("%s sli %d has no query", sliType.Name, sli.Id)
+	}
+	tuples, err := p.QueryRange(query, from, to)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
+	return buildModel(sli.Id, tuples, sliType, stepSeconds(p))
+}
+
+// stepSeconds is the query resolution of p, which is also how long each raw data point lasts
+func stepSeconds(p *clients.PrometheusClient) int {
+	return int(p.Step().Seconds())
+}
+
+// fetcher queries the raw data of a window, with the number of samples that had no value
+type fetcher func(from time.Time, to time.Time) ([]models.SliRawDataBody, int, error)
+
+// sink receives the raw data of each window, posting it or recording it for a preview
+type sink func(from time.Time, to time.Time, rawDatas []models.SliRawDataBody, skipped int) error
+
+// metricPathFetcher queries the SLI's metric path, it also returns the SLI type name
+func metricPathFetcher(p *clients.PrometheusClient, sli *models.SliBody) (fetcher, string, error) {
 	resp, err := sli.GetSliType()
+	if err != nil {
+		return nil, "", err
+	}
+	mp, err := sli.DecodeMetricPath()
+	if err != nil {
+		return nil, "", err
+	}
+	return func(from time.Time, to time.Time) ([]models.SliRawDataBody, int, error) {
+		return queryRawData(p, sli, resp.SliType, mp, from, to)
+	}, resp.SliType.Name, nil
+}
+
+// deliverer posts each window, a chunk kept in the outbox is replayed later so the rest
+// of the range is still posted
+func deliverer(sliType string) sink {
+	c := clients.NewBlamelessClient()
+	return func(from time.Time, to time.Time, rawDatas []models.SliRawDataBody, skipped int) error {
+		if len(rawDatas) == 0 {
+			return nil
+		}
+		if _, err := deliver(c, sliType, rawDatas); err != nil && !errors.Is(err, outbox.ErrQueued) {
+			return err
+		}
+		return nil
+	}
+}
+
+// deliver posts raw data in batches, batches that fail with a retryable error are kept in the outbox for replay
+func deliver(c *clients.BlamelessClient, sliType string, rawDatas []models.SliRawDataBody) (*models.PostManyResponse, error) {
+	req, err := models.NewPostManyRequest(sliType, rawDatas)
+	if err != nil {
+		return nil, err
+	}
+	opts := models.DefaultBatchOptions()
+	batches, err := req.Split(opts)
+	if err != nil {
+		return nil, err
+	}
+	results := models.PostBatches(c, batches, opts)
+	if len(results.Errors) == 0 {
+		return results, nil
+	}
+
+	box, err := outbox.Default()
+	if err != nil {
+		return results, fmt.Errorf("%v, and the outbox is unavailable: %v", results.Err(), err)
+	}
+	rejected := []string{}
+	for _, failed := range results.Errors {
+		// A rejected batch would be rejected again on every replay and hold up the
+		// batches queued after it, only failures that may pass later are queued
+		if !clients.Retryable(failed.Err) {
+			rejected = append(rejected, failed.Error())
+			continue
+		}
+		batch, err := box.Enqueue(failed.Request, failed.Err)
+		if err != nil {
+			return results, fmt.Errorf("%v, and the batch could not be queued: %v", failed, err)
+		}
+		slog.Warn("raw data queued in outbox", "batch", batch.Id, "points", len(failed.Request.RawData), "error", failed.Err)
+	}
+	if len(rejected) > 0 {
+		return results, fmt.Errorf("raw data rejected, not queued: %s", strings.Join(rejected, "; "))
+	}
+	return results, fmt.Errorf("%v: %w", results.Err(), outbox.ErrQueued)
+}
+
+// Post delivers raw data the way ingest does, batches that fail are kept in the outbox
+func Post(sliType string, rawDatas []models.SliRawDataBody) (*models.PostManyResponse, error) {
+	return deliver(clients.NewBlamelessClient(), sliType, rawDatas)
+}
+
+// Window is a range raw data is queried and posted for at once, [From, To)
+type Window struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// Windows splits [from, to) into consecutive windows of size, the last one may be shorter
+func Windows(from time.Time, to time.Time, size time.Duration) []Window {
+	windows := []Window{}
+	for start := from; start.Before(to); start = start.Add(size) {
+		end := start.Add(size)
+		if end.After(to) {
+			end = to
+		}
+		windows = append(windows, Window{From: start, To: end})
+	}
+	return windows
+}
+
+func backfillWindow(fetch fetcher, post sink, w Window) error {
+	rawDatas, skipped, err := fetch(w.From, w.To)
+	if err != nil {
+		return err
+	}
+	// The sample at To opens the next window, posting it twice would store a duplicate
+	return post(w.From, w.To, startingBefore(rawDatas, w.To), skipped)
+}
+
+// RegularSli ingests the last ingest.period from the queries on the SLI's metric path.
+// When some batches are kept in the outbox the results are returned with outbox.ErrQueued.
+func RegularSli(p *clients.PrometheusClient, sli *models.SliBody) (*models.PostManyResponse, error) {
+	fetch, sliType, err := metricPathFetcher(p, sli)
 	if err != nil {
 		return nil, err
 	}
-	rawDatas, err := buildModel(sli.Id, tuples, resp.SliType)
+	return regular(fetch, sliType)
+}
+
+func regular(fetch fetcher, sliType string) (*models.PostManyResponse, error) {
+	start, end := regularRange()
+	rawDatas, _, err := fetch(start, end)
 	if err != nil {
 		return nil, err
 	}
-	results, err := models.PostMany(clients.NewBlamelessClient(), rawDatas)
+	// Batches kept in the outbox are listed in the results with outbox.ErrQueued
+	return deliver(clients.NewBlamelessClient(), sliType, rawDatas)
+}
+
+// regularRange is the range a regular ingest covers, the last ingest.period up to now
+func regularRange() (time.Time, time.Time) {
+	now := time.Now()
+	return now.Add(time.Duration(-config.Environment().Ingest.Period/60) * time.Minute), now
+}
+
+// Fetch queries the raw data of an SLI starting in [from, to) without posting it. The
+// range is queried a day at a time to stay under the points Prometheus returns per query.
+func Fetch(p *clients.PrometheusClient, sli *models.SliBody, from time.Time, to time.Time) ([]models.SliRawDataBody, error) {
+	fetch, _, err := metricPathFetcher(p, sli)
 	if err != nil {
 		return nil, err
 	}
-	return results, nil
+	rawDatas := []models.SliRawDataBody{}
+	for start := from; start.Before(to); start = start.Add(24 * time.Hour) {
+		end := start.Add(24 * time.Hour)
+		if end.After(to) {
+			end = to
+		}
+		chunk, _, err := fetch(start, end)
+		if err != nil {
+			return nil, err
+		}
+		rawDatas = append(rawDatas, startingBefore(chunk, end)...)
+	}
+	return rawDatas, nil
 }
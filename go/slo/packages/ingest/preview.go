@@ -0,0 +1,141 @@
+package ingest
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/clients"
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+)
+
+// previewSampleSize is how many payloads a preview keeps as a sample
+const previewSampleSize = 3
+
+// FieldStats summarizes the values of one raw data field
+type FieldStats struct {
+	Field string  `json:"field"`
+	Min   int     `json:"min"`
+	Max   int     `json:"max"`
+	Avg   float64 `json:"avg"`
+
+	count int
+	sum   int
+}
+
+// PreviewWindow is one query window an ingest would post
+type PreviewWindow struct {
+	From   time.Time     `json:"from"`
+	To     time.Time     `json:"to"`
+	Points int           `json:"points"`
+	NaN    int           `json:"nan"` // Samples without a value, which are not posted
+	Fields []*FieldStats `json:"fields"`
+}
+
+// Preview is what an ingest would post, built by running its queries without posting
+type Preview struct {
+	SliId   int                     `json:"sliId"`
+	SliType string                  `json:"sliType"`
+	Windows []*PreviewWindow        `json:"windows"`
+	Points  int                     `json:"points"`
+	NaN     int                     `json:"nan"`
+	Fields  []*FieldStats           `json:"fields"`
+	Sample  []models.SliRawDataBody `json:"sample"`
+
+	rawData []models.SliRawDataBody
+}
+
+func newPreview(sli *models.SliBody, sliType string) *Preview {
+	return &Preview{
+		SliId:   sli.Id,
+		SliType: sliType,
+		Windows: []*PreviewWindow{},
+		Fields:  newFieldStats(sliType),
+		Sample:  []models.SliRawDataBody{},
+		rawData: []models.SliRawDataBody{},
+	}
+}
+
+// PreviewBackfill runs the queries of BackfillRange over the pending windows of an SLI without posting
+func PreviewBackfill(p *clients.PrometheusClient, sli *models.SliBody, state *BackfillState) (*Preview, error) {
+	fetch, sliType, err := metricPathFetcher(p.WithStep(state.step()), sli)
+	if err != nil {
+		return nil, err
+	}
+	preview := newPreview(sli, sliType)
+	for _, w := range state.Pending(sli.Id) {
+		if err := backfillWindow(fetch, preview.add, w); err != nil {
+			return nil, err
+		}
+	}
+	return preview, nil
+}
+
+// PreviewRegular runs the queries of RegularSli without posting
+func PreviewRegular(p *clients.PrometheusClient, sli *models.SliBody) (*Preview, error) {
+	fetch, sliType, err := metricPathFetcher(p, sli)
+	if err != nil {
+		return nil, err
+	}
+	preview := newPreview(sli, sliType)
+	start, end := regularRange()
+	rawDatas, skipped, err := fetch(start, end)
+	if err != nil {
+		return nil, err
+	}
+	if err := preview.add(start, end, rawDatas, skipped); err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
+// add records a window, it is the sink of a preview
+func (p *Preview) add(from time.Time, to time.Time, rawDatas []models.SliRawDataBody, skipped int) error {
+	w := &PreviewWindow{From: from, To: to, Points: len(rawDatas), NaN: skipped, Fields: newFieldStats(p.SliType)}
+	for i := range rawDatas {
+		for j, f := range w.Fields {
+			value := rawDatas[i].Value(f.Field)
+			f.observe(value)
+			p.Fields[j].observe(value)
+		}
+		if len(p.Sample) < previewSampleSize {
+			p.Sample = append(p.Sample, rawDatas[i])
+		}
+	}
+	p.Windows = append(p.Windows, w)
+	p.Points += len(rawDatas)
+	p.NaN += skipped
+	p.rawData = append(p.rawData, rawDatas...)
+	return nil
+}
+
+// WritePayload writes the full request the ingest would post, before it is split into batches
+func (p *Preview) WritePayload(w io.Writer) error {
+	req, err := models.NewPostManyRequest(p.SliType, p.rawData)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(req)
+}
+
+func newFieldStats(sliType string) []*FieldStats {
+	stats := []*FieldStats{}
+	for _, f := range models.RawDataFields(sliType) {
+		stats = append(stats, &FieldStats{Field: f})
+	}
+	return stats
+}
+
+func (f *FieldStats) observe(value int) {
+	if f.count == 0 || value < f.Min {
+		f.Min = value
+	}
+	if f.count == 0 || value > f.Max {
+		f.Max = value
+	}
+	f.count++
+	f.sum += value
+	f.Avg = float64(f.sum) / float64(f.count)
+}
@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/config"
+)
+
+const redacted = "[REDACTED]"
+
+// Attribute keys that may carry credentials, their values are always redacted
+var sensitiveKeys = []string{
+	"authorization",
+	"token",
+	"password",
+	"secret",
+	"cookie",
+}
+
+// Setup installs the default slog logger configured by the log section of config
+func Setup(cfg config.Log) {
+	slog.SetDefault(New(os.Stderr, cfg))
+	// SetDefault routes the log package through slog, CLI errors from log.Fatal stay plain text
+	log.SetOutput(os.Stderr)
+}
+
+// New creates a logger that writes to w at the configured level and format
+func New(w io.Writer, cfg config.Log) *slog.Logger {
+	opts := &slog.HandlerOptions{
+		Level:       ParseLevel(cfg.Level),
+		ReplaceAttr: redact,
+	}
+	if strings.EqualFold(cfg.Format, "json") {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// ParseLevel maps debug, info, warn and error to a slog level, defaulting to info
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// IsSensitive reports whether a header or attribute name may carry a credential
+func IsSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func redact(groups []string, a slog.Attr) slog.Attr {
+	if IsSensitive(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+	return a
+}
+
+// Headers returns a copy of h that is safe to log
+func Headers(h http.Header) http.Header {
+	safe := make(http.Header, len(h))
+	for k, v := range h {
+		if IsSensitive(k) {
+			safe[k] = []string{redacted}
+			continue
+		}
+		safe[k] = v
+	}
+	return safe
+}
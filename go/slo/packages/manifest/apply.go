@@ -0,0 +1,57 @@
+package manifest
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+)
+
+// Apply makes the planned changes in order and stops at the first failure. Changes made
+// before it stay in place, planning and applying again picks up where it stopped.
+func (p *Plan) Apply() error {
+	for _, c := range p.Changes {
+		if c.Action == Unchanged {
+			continue
+		}
+		if err := p.apply(c); err != nil {
+			return fmt.Errorf("unable to %s %s %q: %w", c.Action, c.Kind, c.Name, err)
+		}
+		slog.Info("applied change", "action", c.Action, "kind", c.Kind, "name", c.Name, "id", c.Id)
+	}
+	return nil
+}
+
+func (p *Plan) apply(c *Change) error {
+	switch {
+	case c.Kind == Kinds.Sli && c.Action == Create:
+		resp, err := models.PostSli(&models.PostSliRequest{OrgId: p.OrgId, Model: c.sli})
+		if err != nil {
+			return err
+		}
+		c.Id = resp.Sli.Id
+	case c.Kind == Kinds.Sli && c.Action == Update:
+		_, err := models.UpdateSli(&models.UpdateSliRequest{OrgId: p.OrgId, Id: c.Id, Model: c.sli})
+		return err
+	case c.Kind == Kinds.Sli && c.Action == Delete:
+		_, err := models.DeleteSli(&models.DeleteSliRequest{OrgId: p.OrgId, Id: c.Id})
+		return err
+	case c.Kind == Kinds.Slo && c.Action == Create:
+		if c.parent.Id == 0 {
+			return fmt.Errorf("sli %q was not created", c.parent.Name)
+		}
+		c.slo.SliId = c.parent.Id
+		resp, err := models.PostSlo(&models.PostSloRequest{OrgId: p.OrgId, Model: c.slo})
+		if err != nil {
+			return err
+		}
+		c.Id = resp.Slo.Id
+	case c.Kind == Kinds.Slo && c.Action == Update:
+		_, err := models.UpdateSlo(&models.UpdateSloRequest{OrgId: p.OrgId, Id: c.Id, Model: c.slo})
+		return err
+	case c.Kind == Kinds.Slo && c.Action == Delete:
+		_, err := models.DeleteSlo(&models.DeleteSloRequest{OrgId: p.OrgId, Id: c.Id})
+		return err
+	}
+	return nil
+}
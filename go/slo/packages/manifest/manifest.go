@@ -0,0 +1,134 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest declares the SLIs, and the SLOs built on them, that should exist in Blameless.
+// SLIs are matched by name within the org and SLOs by name within their SLI.
+type Manifest struct {
+	OrgId int    `json:"orgId,omitempty" yaml:"orgId,omitempty"`
+	Slis  []*Sli `json:"slis" yaml:"slis"`
+}
+
+type Sli struct {
+	Name        string             `json:"name" yaml:"name"`
+	Description string             `json:"description,omitempty" yaml:"description,omitempty"`
+	ServiceId   int                `json:"serviceId" yaml:"serviceId"`
+	Type        string             `json:"type" yaml:"type"` // SLI type name, see sli types
+	MetricPath  *models.MetricPath `json:"metricPath" yaml:"metricPath"`
+	Slos        []*Slo             `json:"slos,omitempty" yaml:"slos,omitempty"`
+}
+
+type Slo struct {
+	Name                 string            `json:"name" yaml:"name"`
+	Description          string            `json:"description,omitempty" yaml:"description,omitempty"`
+	ObjectivePercentage  float64           `json:"objectivePercentage" yaml:"objectivePercentage"`
+	Window               *models.SloWindow `json:"window" yaml:"window"`
+	Threshold            float64           `json:"threshold,omitempty" yaml:"threshold,omitempty"`
+	ErrorBudgetPolicyIds []int             `json:"errorBudgetPolicyIds,omitempty" yaml:"errorBudgetPolicyIds,omitempty"`
+}
+
+// Load reads a YAML or JSON manifest, "-" reads from stdin. Unknown fields are
+// rejected so a misspelt key fails instead of being silently dropped.
+func Load(path string) (*Manifest, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read manifest: %w", err)
+	}
+
+	m := &Manifest{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(m)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(m)
+		if err == io.EOF {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse manifest %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Validate checks names are present and unique, types exist in the catalog and each
+// metric path and SLO fits its SLI type. Type names are normalized to the catalog's.
+func (m *Manifest) Validate(catalog []*models.SliTypeBody) error {
+	slis := map[string]bool{}
+	for i, sli := range m.Slis {
+		if sli.Name == "" {
+			return fmt.Errorf("sli %d needs a name", i)
+		}
+		if slis[sli.Name] {
+			return fmt.Errorf("sli %q is declared more than once", sli.Name)
+		}
+		slis[sli.Name] = true
+
+		st := findSliType(catalog, sli.Type)
+		if st == nil {
+			return fmt.Errorf("sli %q has unknown type %q, see sli types", sli.Name, sli.Type)
+		}
+		sli.Type = st.Name
+		if sli.MetricPath == nil {
+			return fmt.Errorf("sli %q needs a metricPath", sli.Name)
+		}
+		if err := sli.MetricPath.Validate(sli.Type); err != nil {
+			return fmt.Errorf("sli %q: %v", sli.Name, err)
+		}
+
+		slos := map[string]bool{}
+		for j, slo := range sli.Slos {
+			if slo.Name == "" {
+				return fmt.Errorf("slo %d of sli %q needs a name", j, sli.Name)
+			}
+			if slos[slo.Name] {
+				return fmt.Errorf("slo %q is declared more than once on sli %q", slo.Name, sli.Name)
+			}
+			slos[slo.Name] = true
+			if err := slo.body().Validate(sli.Type); err != nil {
+				return fmt.Errorf("slo %q of sli %q: %v", slo.Name, sli.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func findSliType(catalog []*models.SliTypeBody, name string) *models.SliTypeBody {
+	for _, st := range catalog {
+		if strings.EqualFold(st.Name, name) {
+			return st
+		}
+	}
+	return nil
+}
+
+func (s *Slo) body() *models.SloBody {
+	return &models.SloBody{
+		Name:                 s.Name,
+		Description:          s.Description,
+		ObjectivePercentage:  s.ObjectivePercentage,
+		Window:               s.Window,
+		Threshold:            s.Threshold,
+		ErrorBudgetPolicyIds: s.ErrorBudgetPolicyIds,
+	}
+}
@@ -0,0 +1,335 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+)
+
+type Action string
+
+const (
+	Create    Action = "create"
+	Update    Action = "update"
+	Delete    Action = "delete"
+	Unchanged Action = "unchanged"
+)
+
+var Kinds = &ChangeKinds{
+	Sli: "sli",
+	Slo: "slo",
+}
+
+type ChangeKinds struct {
+	Sli string
+	Slo string
+}
+
+// FieldDiff is one field of an SLI or SLO whose value in Blameless differs from the manifest
+type FieldDiff struct {
+	Field   string `json:"field"`
+	Current string `json:"current"`
+	Desired string `json:"desired"`
+}
+
+// Change is what applying the manifest does to one SLI or SLO
+type Change struct {
+	Action Action      `json:"action"`
+	Kind   string      `json:"kind"`
+	Name   string      `json:"name"`
+	Sli    string      `json:"sli,omitempty"` // Name of the SLI an SLO is built on
+	Id     int         `json:"id,omitempty"`
+	Fields []FieldDiff `json:"fields,omitempty"`
+
+	sli    *models.SliBody
+	slo    *models.SloBody
+	parent *Change // SLI change of an SLO, its Id is known once the SLI is created
+}
+
+// Plan lists the changes that converge Blameless on a manifest, in the order they are applied
+type Plan struct {
+	OrgId   int       `json:"orgId"`
+	Changes []*Change `json:"changes"`
+}
+
+type field struct {
+	name  string
+	value string
+}
+
+// NewPlan compares the manifest with the SLIs and SLOs in Blameless. With prune, SLIs
+// of the services the manifest declares and SLOs of its SLIs that the manifest does not
+// list are deleted. SLIs of other services are never touched.
+func NewPlan(m *Manifest, orgId int, prune bool) (*Plan, error) {
+	catalog, err := models.CachedSliTypes()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Validate(catalog); err != nil {
+		return nil, err
+	}
+	typeNames := map[int]string{}
+	for _, st := range catalog {
+		typeNames[st.Id] = st.Name
+	}
+
+	existing, err := models.ListAllSlis(&models.ListSlisRequest{OrgId: orgId})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list SLIs: %w", err)
+	}
+	byName := map[string]*models.SliBody{}
+	for _, s := range existing {
+		if _, ok := byName[s.Name]; ok {
+			return nil, fmt.Errorf("blameless has more than one SLI named %q, rename one before applying", s.Name)
+		}
+		byName[s.Name] = s
+	}
+
+	p := &Plan{OrgId: orgId, Changes: []*Change{}}
+	declared := map[string]bool{}
+	services := map[int]bool{}
+	for _, sli := range m.Slis {
+		declared[sli.Name] = true
+		services[sli.ServiceId] = true
+
+		mp, err := json.Marshal(sli.MetricPath)
+		if err != nil {
+			return nil, err
+		}
+		desired := &models.SliBody{
+			Name:         sli.Name,
+			Description:  sli.Description,
+			DataSourceId: models.BlamelessSourceId,
+			SliTypeId:    findSliType(catalog, sli.Type).Id,
+			ServiceId:    sli.ServiceId,
+			MetricPath:   string(mp),
+		}
+		change := &Change{Action: Create, Kind: Kinds.Sli, Name: sli.Name, sli: desired}
+
+		var currentSlos []*models.SloBody
+		if listed, ok := byName[sli.Name]; ok {
+			resp, err := models.GetSli(&models.GetSliRequest{OrgId: orgId, Id: listed.Id})
+			if err != nil {
+				return nil, fmt.Errorf("unable to fetch SLI %q: %w", sli.Name, err)
+			}
+			current := resp.Sli
+			currentFields, err := sliFields(current, typeNames[current.SliTypeId])
+			if err != nil {
+				return nil, err
+			}
+			desiredFields, err := sliFields(desired, sli.Type)
+			if err != nil {
+				return nil, err
+			}
+			updated := *current
+			updated.Name = desired.Name
+			updated.Description = desired.Description
+			updated.SliTypeId = desired.SliTypeId
+			updated.ServiceId = desired.ServiceId
+			updated.MetricPath = desired.MetricPath
+
+			change.Id = current.Id
+			change.sli = &updated
+			change.Fields = diff(currentFields, desiredFields)
+			change.Action = Update
+			if len(change.Fields) == 0 {
+				change.Action = Unchanged
+			}
+
+			currentSlos, err = models.ListAllSlos(&models.ListSlosRequest{OrgId: orgId, SliId: current.Id})
+			if err != nil {
+				return nil, fmt.Errorf("unable to list SLOs of SLI %q: %w", sli.Name, err)
+			}
+		} else {
+			fields, err := sliFields(desired, sli.Type)
+			if err != nil {
+				return nil, err
+			}
+			change.Fields = diff(nil, fields)
+		}
+		p.Changes = append(p.Changes, change)
+
+		sloChanges, err := planSlos(change, sli.Slos, currentSlos, prune)
+		if err != nil {
+			return nil, err
+		}
+		p.Changes = append(p.Changes, sloChanges...)
+	}
+
+	if prune {
+		for _, s := range existing {
+			if declared[s.Name] || !services[s.ServiceId] {
+				continue
+			}
+			change := &Change{Action: Delete, Kind: Kinds.Sli, Name: s.Name, Id: s.Id}
+			fields, err := sliFields(s, typeNames[s.SliTypeId])
+			if err != nil {
+				return nil, err
+			}
+			change.Fields = diff(fields, nil)
+
+			slos, err := models.ListAllSlos(&models.ListSlosRequest{OrgId: orgId, SliId: s.Id})
+			if err != nil {
+				return nil, fmt.Errorf("unable to list SLOs of SLI %q: %w", s.Name, err)
+			}
+			sloChanges, err := planSlos(change, nil, slos, true)
+			if err != nil {
+				return nil, err
+			}
+			// An SLI is deleted after the SLOs built on it
+			p.Changes = append(p.Changes, sloChanges...)
+			p.Changes = append(p.Changes, change)
+		}
+	}
+	return p, nil
+}
+
+// planSlos compares the SLOs declared on an SLI with those built on it in Blameless
+func planSlos(parent *Change, declared []*Slo, existing []*models.SloBody, prune bool) ([]*Change, error) {
+	byName := map[string]*models.SloBody{}
+	for _, s := range existing {
+		if _, ok := byName[s.Name]; ok {
+			return nil, fmt.Errorf("SLI %q has more than one SLO named %q, rename one before applying", parent.Name, s.Name)
+		}
+		byName[s.Name] = s
+	}
+
+	changes := []*Change{}
+	names := map[string]bool{}
+	for _, slo := range declared {
+		names[slo.Name] = true
+		desired := slo.body()
+		change := &Change{Action: Create, Kind: Kinds.Slo, Name: slo.Name, Sli: parent.Name, slo: desired, parent: parent}
+		if current, ok := byName[slo.Name]; ok {
+			updated := *current
+			updated.Description = desired.Description
+			updated.ObjectivePercentage = desired.ObjectivePercentage
+			updated.Window = desired.Window
+			updated.Threshold = desired.Threshold
+			updated.ErrorBudgetPolicyIds = desired.ErrorBudgetPolicyIds
+
+			change.Id = current.Id
+			change.slo = &updated
+			change.Fields = diff(sloFields(current), sloFields(desired))
+			change.Action = Update
+			if len(change.Fields) == 0 {
+				change.Action = Unchanged
+			}
+		} else {
+			change.Fields = diff(nil, sloFields(desired))
+		}
+		changes = append(changes, change)
+	}
+
+	if prune {
+		for _, s := range existing {
+			if names[s.Name] {
+				continue
+			}
+			changes = append(changes, &Change{
+				Action: Delete,
+				Kind:   Kinds.Slo,
+				Name:   s.Name,
+				Sli:    parent.Name,
+				Id:     s.Id,
+				Fields: diff(sloFields(s), nil),
+			})
+		}
+	}
+	return changes, nil
+}
+
+// sliFields lists the compared fields of an SLI, one per metric path query
+func sliFields(s *models.SliBody, typeName string) ([]field, error) {
+	mp, err := s.DecodeMetricPath()
+	if err != nil {
+		return nil, err
+	}
+	fields := []field{
+		{"name", s.Name},
+		{"description", s.Description},
+		{"type", typeName},
+		{"serviceId", strconv.Itoa(s.ServiceId)},
+	}
+	if mp.Availability != nil {
+		fields = append(fields,
+			field{"metricPath.availability.goodRequest", mp.Availability.GoodRequest},
+			field{"metricPath.availability.validRequest", mp.Availability.ValidRequest},
+		)
+	}
+	fields = append(fields,
+		field{"metricPath.latency", mp.Latency},
+		field{"metricPath.throughput", mp.Throughput},
+		field{"metricPath.saturation", mp.Saturation},
+		field{"metricPath.correctness", mp.Correctness},
+		field{"metricPath.durability", mp.Durability},
+	)
+	return fields, nil
+}
+
+func sloFields(s *models.SloBody) []field {
+	policies := make([]string, len(s.ErrorBudgetPolicyIds))
+	for i, id := range s.ErrorBudgetPolicyIds {
+		policies[i] = strconv.Itoa(id)
+	}
+	return []field{
+		{"name", s.Name},
+		{"description", s.Description},
+		{"objectivePercentage", formatFloat(s.ObjectivePercentage)},
+		{"window", s.Window.String()},
+		{"threshold", formatFloat(s.Threshold)},
+		{"errorBudgetPolicyIds", strings.Join(policies, ",")},
+	}
+}
+
+func formatFloat(f float64) string {
+	if f == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// diff returns the fields whose value differs, in the order they are listed. A field
+// missing from one side compares as empty, so creates and deletes list every set field.
+func diff(current []field, desired []field) []FieldDiff {
+	values := map[string]*FieldDiff{}
+	order := []string{}
+	for _, f := range desired {
+		values[f.name] = &FieldDiff{Field: f.name, Desired: f.value}
+		order = append(order, f.name)
+	}
+	for _, f := range current {
+		if d, ok := values[f.name]; ok {
+			d.Current = f.value
+			continue
+		}
+		values[f.name] = &FieldDiff{Field: f.name, Current: f.value}
+		order = append(order, f.name)
+	}
+	diffs := []FieldDiff{}
+	for _, name := range order {
+		if d := values[name]; d.Current != d.Desired {
+			diffs = append(diffs, *d)
+		}
+	}
+	return diffs
+}
+
+// Count returns how many changes take the action
+func (p *Plan) Count(action Action) int {
+	n := 0
+	for _, c := range p.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// HasChanges reports whether Blameless differs from the manifest
+func (p *Plan) HasChanges() bool {
+	return p.Count(Unchanged) != len(p.Changes)
+}
@@ -0,0 +1,260 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+)
+
+// blameless holds the SLIs and SLOs the fake Blameless API of these tests serves
+var blameless struct {
+	slis []*models.SliBody
+	slos []*models.SloBody
+}
+
+func serveBlameless(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Id    int `json:"id"`
+		SliId int `json:"sliId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var resp any
+	switch path.Base(r.URL.Path) {
+	case "ListSliTypes":
+		resp = models.ListSliTypesResponse{SliTypes: []*models.SliTypeBody{{Id: 1, Name: "Availability"}, {Id: 2, Name: "Latency"}}}
+	case "ListSLIs":
+		resp = map[string]any{"slis": blameless.slis, "total": len(blameless.slis)}
+	case "GetSLI":
+		for _, s := range blameless.slis {
+			if s.Id == req.Id {
+				resp = map[string]any{"sli": s}
+			}
+		}
+	case "ListSLOs":
+		slos := []*models.SloBody{}
+		for _, s := range blameless.slos {
+			if s.SliId == req.SliId {
+				slos = append(slos, s)
+			}
+		}
+		resp = map[string]any{"slos": slos, "total": len(slos)}
+	}
+	if resp == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// TestMain points config at the fake Blameless API, config is read from the working directory
+func TestMain(m *testing.M) {
+	srv := httptest.NewServer(http.HandlerFunc(serveBlameless))
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		panic(err)
+	}
+	dir, err := os.MkdirTemp("", "manifest")
+	if err != nil {
+		panic(err)
+	}
+	cfg := fmt.Sprintf(`blameless:
+  host: "http://%s"
+  port: %s
+  orgId: 1
+  authToken: "test"
+http:
+  retry:
+    count: 0
+cache:
+  ttl: 0
+`, u.Hostname(), u.Port())
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(cfg), 0o600); err != nil {
+		panic(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	srv.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func checkoutManifest() *Manifest {
+	return &Manifest{Slis: []*Sli{{
+		Name:        "checkout",
+		Description: "checkout requests",
+		ServiceId:   7,
+		Type:        "availability",
+		MetricPath:  &models.MetricPath{Availability: &models.AvailabilityStruct{GoodRequest: "good", ValidRequest: "valid"}},
+		Slos: []*Slo{{
+			Name:                "checkout 99.9",
+			ObjectivePercentage: 99.9,
+			Window:              &models.SloWindow{Type: "rolling", Days: 28},
+		}},
+	}}}
+}
+
+func sli(id int, name string, serviceId int, description string) *models.SliBody {
+	return &models.SliBody{
+		OrgId:        1,
+		Id:           id,
+		Name:         name,
+		Description:  description,
+		DataSourceId: models.BlamelessSourceId,
+		SliTypeId:    1,
+		ServiceId:    serviceId,
+		MetricPath:   `{"availability":{"goodRequest":"good","validRequest":"valid"}}`,
+	}
+}
+
+func slo(id int, sliId int, name string, objective float64) *models.SloBody {
+	return &models.SloBody{
+		Id:                  id,
+		SliId:               sliId,
+		Name:                name,
+		ObjectivePercentage: objective,
+		Window:              &models.SloWindow{Type: "rolling", Days: 28},
+	}
+}
+
+func TestNewPlan(t *testing.T) {
+	checkout := sli(10, "checkout", 7, "checkout requests")
+	checkoutSlo := slo(20, 10, "checkout 99.9", 99.9)
+	undeclared := []*models.SliBody{checkout, sli(11, "legacy", 7, ""), sli(12, "other service", 8, "")}
+	undeclaredSlos := []*models.SloBody{checkoutSlo, slo(21, 11, "legacy 99", 99), slo(22, 10, "old", 95)}
+	tests := []struct {
+		name    string
+		slis    []*models.SliBody
+		slos    []*models.SloBody
+		prune   bool
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "create",
+			want: []string{"create sli checkout", "create slo checkout 99.9"},
+		},
+		{
+			name: "unchanged",
+			slis: []*models.SliBody{checkout},
+			slos: []*models.SloBody{checkoutSlo},
+			want: []string{"unchanged sli checkout", "unchanged slo checkout 99.9"},
+		},
+		{
+			name: "update",
+			slis: []*models.SliBody{sli(10, "checkout", 7, "old description")},
+			slos: []*models.SloBody{slo(20, 10, "checkout 99.9", 99.5)},
+			want: []string{"update sli checkout", "update slo checkout 99.9"},
+		},
+		{
+			name: "undeclared are kept without prune",
+			slis: undeclared,
+			slos: undeclaredSlos,
+			want: []string{"unchanged sli checkout", "unchanged slo checkout 99.9"},
+		},
+		{
+			name:  "prune deletes undeclared of declared services",
+			slis:  undeclared,
+			slos:  undeclaredSlos,
+			prune: true,
+			want:  []string{"unchanged sli checkout", "unchanged slo checkout 99.9", "delete slo old", "delete slo legacy 99", "delete sli legacy"},
+		},
+		{
+			name:    "duplicate sli names",
+			slis:    []*models.SliBody{checkout, sli(11, "checkout", 8, "")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blameless.slis = tt.slis
+			blameless.slos = tt.slos
+			p, err := NewPlan(checkoutManifest(), 1, tt.prune)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewPlan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := []string{}
+			for _, c := range p.Changes {
+				got = append(got, fmt.Sprintf("%s %s %s", c.Action, c.Kind, c.Name))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewPlan() changes = %q, want %q", got, tt.want)
+			}
+			changed := false
+			for _, c := range tt.want {
+				changed = changed || !strings.HasPrefix(c, string(Unchanged))
+			}
+			if p.HasChanges() != changed {
+				t.Errorf("HasChanges() = %v, want %v", p.HasChanges(), changed)
+			}
+		})
+	}
+}
+
+func TestNewPlanFields(t *testing.T) {
+	blameless.slis = []*models.SliBody{sli(10, "checkout", 7, "old description")}
+	blameless.slos = []*models.SloBody{slo(20, 10, "checkout 99.9", 99.5)}
+	p, err := NewPlan(checkoutManifest(), 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		change *Change
+		id     int
+		want   []FieldDiff
+	}{
+		{change: p.Changes[0], id: 10, want: []FieldDiff{{Field: "description", Current: "old description", Desired: "checkout requests"}}},
+		{change: p.Changes[1], id: 20, want: []FieldDiff{{Field: "objectivePercentage", Current: "99.5", Desired: "99.9"}}},
+	}
+	for _, tt := range tests {
+		if tt.change.Id != tt.id || !reflect.DeepEqual(tt.change.Fields, tt.want) {
+			t.Errorf("%s %s = id %d %+v, want id %d %+v", tt.change.Kind, tt.change.Name, tt.change.Id, tt.change.Fields, tt.id, tt.want)
+		}
+	}
+}
+
+func TestNewPlanCreateListsEveryField(t *testing.T) {
+	blameless.slis = nil
+	blameless.slos = nil
+	p, err := NewPlan(checkoutManifest(), 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		change *Change
+		want   []string
+	}{
+		{change: p.Changes[0], want: []string{"name", "description", "type", "serviceId", "metricPath.availability.goodRequest", "metricPath.availability.validRequest"}},
+		{change: p.Changes[1], want: []string{"name", "objectivePercentage", "window"}},
+	}
+	for _, tt := range tests {
+		got := []string{}
+		for _, f := range tt.change.Fields {
+			if f.Current != "" {
+				t.Errorf("%s %s field %s has current value %q", tt.change.Kind, tt.change.Name, f.Field, f.Current)
+			}
+			got = append(got, f.Field)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %s fields = %v, want %v", tt.change.Kind, tt.change.Name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+)
+
+// Path is where the expvar variables, such as the circuit breaker state, are served
+const Path = "/debug/vars"
+
+// Serve publishes the expvar variables on addr in the background for as long as the
+// command runs. Listening happens before it returns so a taken address is reported.
+func Serve(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("unable to listen on %s: %v", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(Path, expvar.Handler())
+	go func() {
+		if err := http.Serve(l, mux); err != nil {
+			slog.Warn("metrics server stopped", "addr", addr, "error", err)
+		}
+	}()
+	slog.Info("serving metrics", "addr", l.Addr().String(), "path", Path)
+	return nil
+}
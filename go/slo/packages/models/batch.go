@@ -0,0 +1,136 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/clients"
+	"github.com/blamelesshq/blameless-examples/slo/packages/config"
+)
+
+// Room left for the request envelope around the raw data array
+const envelopeBytes = 128
+
+type BatchOptions struct {
+	MaxPoints   int
+	MaxBytes    int
+	Concurrency int
+}
+
+// BatchError records a batch that failed to post, Request can be queued for a later replay
+type BatchError struct {
+	Batch   int
+	Request *PostManyRequest
+	Err     error
+}
+
+func (e *BatchError) Error() string {
+	data := e.Request.RawData
+	return fmt.Sprintf("batch %d (%d points from %d to %d): %v", e.Batch, len(data), data[0].Start, data[len(data)-1].End, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// DefaultBatchOptions reads the limits configured under blameless.batch
+func DefaultBatchOptions() BatchOptions {
+	env := config.Environment().Blameless.Batch
+	return BatchOptions{
+		MaxPoints:   env.MaxPoints,
+		MaxBytes:    env.MaxBytes,
+		Concurrency: env.Concurrency,
+	}
+}
+
+// Split breaks the request into batches holding at most MaxPoints raw data points and
+// roughly MaxBytes of JSON. A limit of zero or less is not enforced.
+func (r *PostManyRequest) Split(opts BatchOptions) ([]*PostManyRequest, error) {
+	batches := []*PostManyRequest{}
+	current := []SliRawDataBody{}
+	size := envelopeBytes
+	for _, d := range r.RawData {
+		b, err := json.Marshal(d.payload(r.SliType))
+		if err != nil {
+			return nil, err
+		}
+		pointBytes := len(b) + 1
+		if opts.MaxBytes > 0 && envelopeBytes+pointBytes > opts.MaxBytes {
+			return nil, fmt.Errorf("a single raw data point of %d bytes exceeds the %d byte batch limit", pointBytes, opts.MaxBytes)
+		}
+		full := opts.MaxPoints > 0 && len(current) >= opts.MaxPoints
+		tooLarge := opts.MaxBytes > 0 && size+pointBytes > opts.MaxBytes
+		if len(current) > 0 && (full || tooLarge) {
+			batches = append(batches, r.withData(current))
+			current = []SliRawDataBody{}
+			size = envelopeBytes
+		}
+		current = append(current, d)
+		size += pointBytes
+	}
+	if len(current) > 0 {
+		batches = append(batches, r.withData(current))
+	}
+	return batches, nil
+}
+
+func (r *PostManyRequest) withData(data []SliRawDataBody) *PostManyRequest {
+	return &PostManyRequest{
+		OrgId:   r.OrgId,
+		SliType: r.SliType,
+		RawData: data,
+	}
+}
+
+// PostBatches posts batches with at most opts.Concurrency in flight. The combined
+// response keeps the order of the batches, failed batches are listed in its Errors.
+func PostBatches(c *clients.BlamelessClient, batches []*PostManyRequest, opts BatchOptions) *PostManyResponse {
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]*PostManyResponse, len(batches))
+	failures := make([]*BatchError, len(batches))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, batch := range batches {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, batch *PostManyRequest) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			resp, err := batch.Post(c)
+			if err != nil {
+				failures[i] = &BatchError{Batch: i, Request: batch, Err: err}
+				return
+			}
+			results[i] = resp
+		}(i, batch)
+	}
+	wg.Wait()
+
+	combined := []SliRawDataBody{}
+	errs := []*BatchError{}
+	for i := range batches {
+		if failures[i] != nil {
+			errs = append(errs, failures[i])
+			continue
+		}
+		if results[i] != nil && results[i].SliRawData != nil {
+			combined = append(combined, *results[i].SliRawData...)
+		}
+	}
+	return &PostManyResponse{SliRawData: &combined, Errors: errs}
+}
+
+// Err joins the per batch errors of a combined response
+func (r *PostManyResponse) Err() error {
+	errs := make([]error, len(r.Errors))
+	for i, e := range r.Errors {
+		errs[i] = e
+	}
+	return errors.Join(errs...)
+}
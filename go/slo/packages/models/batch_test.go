@@ -0,0 +1,87 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func latencies(n int) []SliRawDataBody {
+	data := []SliRawDataBody{}
+	for i := 0; i < n; i++ {
+		data = append(data, SliRawDataBody{SliId: 3, Latency: 100 + i, Start: i * 60, End: (i + 1) * 60})
+	}
+	return data
+}
+
+func TestPostManyRequestSplit(t *testing.T) {
+	tests := []struct {
+		name    string
+		points  int
+		opts    BatchOptions
+		sizes   []int
+		wantErr bool
+	}{
+		{name: "no limits", points: 5, opts: BatchOptions{}, sizes: []int{5}},
+		{name: "max points", points: 5, opts: BatchOptions{MaxPoints: 2}, sizes: []int{2, 2, 1}},
+		{name: "max bytes", points: 5, opts: BatchOptions{MaxBytes: envelopeBytes + 2*60}, sizes: []int{2, 2, 1}},
+		{name: "point over the byte limit", points: 1, opts: BatchOptions{MaxBytes: envelopeBytes + 10}, wantErr: true},
+		{name: "no data", points: 0, opts: BatchOptions{MaxPoints: 2}, sizes: []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &PostManyRequest{OrgId: 1, SliType: "latency", RawData: latencies(tt.points)}
+			batches, err := req.Split(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Split() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(batches) != len(tt.sizes) {
+				t.Fatalf("Split() = %d batches, want %d", len(batches), len(tt.sizes))
+			}
+			next := 0
+			for i, b := range batches {
+				if len(b.RawData) != tt.sizes[i] {
+					t.Errorf("batch %d has %d points, want %d", i, len(b.RawData), tt.sizes[i])
+				}
+				if b.OrgId != req.OrgId || b.SliType != req.SliType {
+					t.Errorf("batch %d = %d/%s, want the request org and sli type", i, b.OrgId, b.SliType)
+				}
+				for _, d := range b.RawData {
+					if d.Start != next*60 {
+						t.Errorf("batch %d point starts at %d, want %d", i, d.Start, next*60)
+					}
+					next++
+				}
+				if tt.opts.MaxBytes > 0 {
+					body, err := json.Marshal(b)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if len(body) > tt.opts.MaxBytes {
+						t.Errorf("batch %d is %d bytes, over the %d byte limit", i, len(body), tt.opts.MaxBytes)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSplitCountsZeroMeasurements(t *testing.T) {
+	// Zero valid and good requests are still posted, so they take room in a batch
+	data := []SliRawDataBody{{SliId: 3, Start: 120, End: 180}, {SliId: 3, Start: 180, End: 240}}
+	req := &PostManyRequest{OrgId: 1, SliType: "availability", RawData: data}
+	point, err := json.Marshal(data[0].payload(req.SliType))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One byte short of room for both points
+	batches, err := req.Split(BatchOptions{MaxBytes: envelopeBytes + 2*(len(point)+1) - 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 2 {
+		t.Errorf("Split() = %d batches, want a batch per point", len(batches))
+	}
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"sync"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/cache"
+	"github.com/blamelesshq/blameless-examples/slo/packages/config"
+)
+
+const catalogKey = "catalog"
+
+type sliKey struct {
+	OrgId int
+	Id    int
+}
+
+var cacheOncer sync.Once
+var sliCache *cache.Cache[sliKey, SliBody]
+var sliTypeCache *cache.Cache[int, SliTypeBody]
+var sliTypeCatalogCache *cache.Cache[string, []*SliTypeBody]
+
+// SLI definitions and types rarely change, lookups are cached for cache.ttl seconds
+// so repeated ingest cycles do not refetch them from Blameless.
+func initCaches() {
+	cacheOncer.Do(func() {
+		ttl := time.Duration(config.Environment().Cache.TTL) * time.Second
+		sliCache = cache.New[sliKey, SliBody](ttl)
+		sliTypeCache = cache.New[int, SliTypeBody](ttl)
+		sliTypeCatalogCache = cache.New[string, []*SliTypeBody](ttl)
+	})
+}
+
+// InvalidateSli drops the cached definition of one SLI
+func InvalidateSli(orgId int, id int) {
+	initCaches()
+	sliCache.Invalidate(sliKey{OrgId: orgId, Id: id})
+}
+
+// Cached values are stored and returned by value so callers can not modify the cache
+func cacheSli(orgId int, sli *SliBody) {
+	if sli == nil {
+		return
+	}
+	initCaches()
+	sliCache.Set(sliKey{OrgId: orgId, Id: sli.Id}, *sli)
+}
+
+func cachedSli(orgId int, id int) (*SliBody, bool) {
+	initCaches()
+	sli, ok := sliCache.Get(sliKey{OrgId: orgId, Id: id})
+	return &sli, ok
+}
+
+func cacheSliType(sliType *SliTypeBody) {
+	if sliType == nil {
+		return
+	}
+	initCaches()
+	sliTypeCache.Set(sliType.Id, *sliType)
+}
+
+func cachedSliType(id int) (*SliTypeBody, bool) {
+	initCaches()
+	sliType, ok := sliTypeCache.Get(id)
+	return &sliType, ok
+}
@@ -0,0 +1,184 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/clients"
+)
+
+// ErrorBudgetPolicyAction is taken once ConsumedPercentage of the error budget is spent
+type ErrorBudgetPolicyAction struct {
+	ConsumedPercentage float64 `json:"consumedPercentage"`
+	Action             string  `json:"action"`
+}
+
+// Errors of Validate for a policy missing its name or actions
+var (
+	ErrPolicyNoName    = errors.New("an error budget policy needs a name")
+	ErrPolicyNoActions = errors.New("an error budget policy needs at least one action")
+)
+
+type ErrorBudgetPolicyBody struct {
+	OrgId       int                       `json:"orgId,omitempty"`
+	Id          int                       `json:"id,omitempty"`
+	Name        string                    `json:"name"`
+	Description string                    `json:"description"`
+	Actions     []ErrorBudgetPolicyAction `json:"actions"`
+	UserId      int                       `json:"userId,omitempty"`
+}
+
+type PostErrorBudgetPolicyRequest struct {
+	OrgId int                    `json:"orgId" binding:"required"`
+	Model *ErrorBudgetPolicyBody `json:"model" binding:"required"`
+}
+
+type GetErrorBudgetPolicyRequest struct {
+	OrgId int `json:"orgId" binding:"required"`
+	Id    int `json:"id" binding:"required"`
+}
+
+type UpdateErrorBudgetPolicyRequest struct {
+	OrgId int                    `json:"orgId" binding:"required"`
+	Id    int                    `json:"id" binding:"required"`
+	Model *ErrorBudgetPolicyBody `json:"model" binding:"required"`
+}
+
+type DeleteErrorBudgetPolicyRequest struct {
+	OrgId int `json:"orgId" binding:"required"`
+	Id    int `json:"id" binding:"required"`
+}
+
+type DeleteErrorBudgetPolicyResponse struct {
+	Success bool `json:"success"`
+}
+
+type ListErrorBudgetPoliciesRequest struct {
+	OrgId    int `json:"orgId" binding:"required"`
+	Page     int `json:"page,omitempty"`
+	PageSize int `json:"pageSize,omitempty"`
+}
+
+type ErrorBudgetPolicyResponse struct {
+	ErrorBudgetPolicy *ErrorBudgetPolicyBody `json:"errorBudgetPolicy"`
+}
+
+type ListErrorBudgetPoliciesResponse struct {
+	ErrorBudgetPolicies []*ErrorBudgetPolicyBody `json:"errorBudgetPolicies"`
+	Total               int                      `json:"total"`
+}
+
+type ErrorBudgetPolicy interface {
+	GetErrorBudgetPolicy(req *GetErrorBudgetPolicyRequest) (*ErrorBudgetPolicyResponse, error)
+	PostErrorBudgetPolicy(req *PostErrorBudgetPolicyRequest) (*ErrorBudgetPolicyResponse, error)
+	UpdateErrorBudgetPolicy(req *UpdateErrorBudgetPolicyRequest) (*ErrorBudgetPolicyResponse, error)
+	DeleteErrorBudgetPolicy(req *DeleteErrorBudgetPolicyRequest) (*DeleteErrorBudgetPolicyResponse, error)
+	ListErrorBudgetPolicies(req *ListErrorBudgetPoliciesRequest) (*ListErrorBudgetPoliciesResponse, error)
+}
+
+// Validate checks the policy is named and every action fires somewhere between an untouched
+// and an exhausted budget
+func (p *ErrorBudgetPolicyBody) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return ErrPolicyNoName
+	}
+	if len(p.Actions) == 0 {
+		return ErrPolicyNoActions
+	}
+	for _, a := range p.Actions {
+		if a.ConsumedPercentage <= 0 || a.ConsumedPercentage > 100 {
+			return fmt.Errorf("action %q must trigger between 0 and 100 percent consumed, got %g", a.Action, a.ConsumedPercentage)
+		}
+		if a.Action == "" {
+			return fmt.Errorf("action at %g percent consumed has no description", a.ConsumedPercentage)
+		}
+	}
+	return nil
+}
+
+func GetErrorBudgetPolicy(req *GetErrorBudgetPolicyRequest) (*ErrorBudgetPolicyResponse, error) {
+	c := clients.NewBlamelessClient()
+	payload, err := json.Marshal(&req)
+	if err != nil {
+		return &ErrorBudgetPolicyResponse{}, err
+	}
+	resp, err := c.Post(c.SloService, "GetErrorBudgetPolicy", payload)
+	if err != nil {
+		return &ErrorBudgetPolicyResponse{}, err
+	}
+	var resultBody *ErrorBudgetPolicyResponse
+	if err := json.Unmarshal(resp, &resultBody); err != nil {
+		return &ErrorBudgetPolicyResponse{}, err
+	}
+	return resultBody, nil
+}
+
+func PostErrorBudgetPolicy(req *PostErrorBudgetPolicyRequest) (*ErrorBudgetPolicyResponse, error) {
+	c := clients.NewBlamelessClient()
+	payload, err := json.Marshal(&req)
+	if err != nil {
+		return &ErrorBudgetPolicyResponse{}, err
+	}
+	resp, err := c.Post(c.SloService, "CreateErrorBudgetPolicy", payload)
+	if err != nil {
+		return &ErrorBudgetPolicyResponse{}, err
+	}
+	var resultBody *ErrorBudgetPolicyResponse
+	if err := json.Unmarshal(resp, &resultBody); err != nil {
+		return &ErrorBudgetPolicyResponse{}, err
+	}
+	return resultBody, nil
+}
+
+func UpdateErrorBudgetPolicy(req *UpdateErrorBudgetPolicyRequest) (*ErrorBudgetPolicyResponse, error) {
+	c := clients.NewBlamelessClient()
+	payload, err := json.Marshal(&req)
+	if err != nil {
+		return &ErrorBudgetPolicyResponse{}, err
+	}
+	resp, err := c.Post(c.SloService, "UpdateErrorBudgetPolicy", payload)
+	if err != nil {
+		return &ErrorBudgetPolicyResponse{}, err
+	}
+	var resultBody *ErrorBudgetPolicyResponse
+	if err := json.Unmarshal(resp, &resultBody); err != nil {
+		return &ErrorBudgetPolicyResponse{}, err
+	}
+	return resultBody, nil
+}
+
+func DeleteErrorBudgetPolicy(req *DeleteErrorBudgetPolicyRequest) (*DeleteErrorBudgetPolicyResponse, error) {
+	c := clients.NewBlamelessClient()
+	payload, err := json.Marshal(&req)
+	if err != nil {
+		return &DeleteErrorBudgetPolicyResponse{}, err
+	}
+	resp, err := c.Post(c.SloService, "DeleteErrorBudgetPolicy", payload)
+	if err != nil {
+		return &DeleteErrorBudgetPolicyResponse{}, err
+	}
+	var resultBody *DeleteErrorBudgetPolicyResponse
+	if err := json.Unmarshal(resp, &resultBody); err != nil {
+		return &DeleteErrorBudgetPolicyResponse{}, err
+	}
+	return resultBody, nil
+}
+
+func ListErrorBudgetPolicies(req *ListErrorBudgetPoliciesRequest) (*ListErrorBudgetPoliciesResponse, error) {
+	c := clients.NewBlamelessClient()
+	payload, err := json.Marshal(&req)
+	if err != nil {
+		return &ListErrorBudgetPoliciesResponse{}, err
+	}
+	resp, err := c.Post(c.SloService, "ListErrorBudgetPolicies", payload)
+	if err != nil {
+		return &ListErrorBudgetPoliciesResponse{}, err
+	}
+	var resultBody *ListErrorBudgetPoliciesResponse
+	if err := json.Unmarshal(resp, &resultBody); err != nil {
+		return &ListErrorBudgetPoliciesResponse{}, err
+	}
+	return resultBody, nil
+}
@@ -2,12 +2,16 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 
 	"github.com/blamelesshq/blameless-examples/slo/packages/clients"
 )
 
 var BlamelessSourceId = 5
 
+var DefaultPageSize = 50
+
 var Types = &SliTypes{
 	Latency:      "Latency",
 	Availability: "Availability",
@@ -27,17 +31,17 @@ type SliTypes struct {
 }
 
 type AvailabilityStruct struct {
-	GoodRequest  string `json:"goodRequest,omitempty"`
-	ValidRequest string `json:"validRequest,omitempty"`
+	GoodRequest  string `json:"goodRequest,omitempty" yaml:"goodRequest,omitempty"`
+	ValidRequest string `json:"validRequest,omitempty" yaml:"validRequest,omitempty"`
 }
 
 type MetricPath struct {
-	Latency      string              `json:"latency,omitempty"`
-	Availability *AvailabilityStruct `json:"availability,omitempty"`
-	Throughput   string              `json:"throughput,omitempty"`
-	Saturation   string              `json:"saturation,omitempty"`
-	Correctness  string              `json:"correctness,omitempty"`
-	Durability   string              `json:"durability,omitempty"`
+	Latency      string              `json:"latency,omitempty" yaml:"latency,omitempty"`
+	Availability *AvailabilityStruct `json:"availability,omitempty" yaml:"availability,omitempty"`
+	Throughput   string              `json:"throughput,omitempty" yaml:"throughput,omitempty"`
+	Saturation   string              `json:"saturation,omitempty" yaml:"saturation,omitempty"`
+	Correctness  string              `json:"correctness,omitempty" yaml:"correctness,omitempty"`
+	Durability   string              `json:"durability,omitempty" yaml:"durability,omitempty"`
 }
 
 type SliBody struct {
@@ -66,6 +70,12 @@ type SliTypeResponse struct {
 	SliType *SliTypeBody `json:"sliType"`
 }
 
+type ListSliTypesRequest struct{}
+
+type ListSliTypesResponse struct {
+	SliTypes []*SliTypeBody `json:"sliTypes"`
+}
+
 type PostSliRequest struct {
 	OrgId int      `json:"orgId" binding:"required"`
 	Model *SliBody `json:"model" binding:"required"`
@@ -80,13 +90,46 @@ type SliResponse struct {
 	Sli *SliBody `json:"sli"`
 }
 
+type UpdateSliRequest struct {
+	OrgId int      `json:"orgId" binding:"required"`
+	Id    int      `json:"id" binding:"required"`
+	Model *SliBody `json:"model" binding:"required"`
+}
+
+type DeleteSliRequest struct {
+	OrgId int `json:"orgId" binding:"required"`
+	Id    int `json:"id" binding:"required"`
+}
+
+type DeleteSliResponse struct {
+	Success bool `json:"success"`
+}
+
+type ListSlisRequest struct {
+	OrgId     int `json:"orgId" binding:"required"`
+	Page      int `json:"page,omitempty"`
+	PageSize  int `json:"pageSize,omitempty"`
+	ServiceId int `json:"serviceId,omitempty"`
+	SliTypeId int `json:"sliTypeId,omitempty"`
+}
+
+type ListSlisResponse struct {
+	Slis  []*SliBody `json:"slis"`
+	Total int        `json:"total"`
+}
+
 type Sli interface {
 	GetSli(req *GetSliRequest) (*SliResponse, error)
 	PostSli(req *PostSliRequest) (*SliResponse, error)
-	Update()
+	UpdateSli(req *UpdateSliRequest) (*SliResponse, error)
+	DeleteSli(req *DeleteSliRequest) (*DeleteSliResponse, error)
+	ListSlis(req *ListSlisRequest) (*ListSlisResponse, error)
 }
 
 func GetSli(req *GetSliRequest) (*SliResponse, error) {
+	if sli, ok := cachedSli(req.OrgId, req.Id); ok {
+		return &SliResponse{Sli: sli}, nil
+	}
 	c := clients.NewBlamelessClient()
 	payload, err := json.Marshal(&req)
 	if err != nil {
@@ -100,6 +143,9 @@ func GetSli(req *GetSliRequest) (*SliResponse, error) {
 	if err := json.Unmarshal(resp, &resultBody); err != nil {
 		return &SliResponse{}, err
 	}
+	if resultBody != nil {
+		cacheSli(req.OrgId, resultBody.Sli)
+	}
 	return resultBody, nil
 }
 
@@ -117,10 +163,96 @@ func PostSli(req *PostSliRequest) (*SliResponse, error) {
 	if err := json.Unmarshal(resp, &resultBody); err != nil {
 		return &SliResponse{}, err
 	}
+	if resultBody != nil {
+		cacheSli(req.OrgId, resultBody.Sli)
+	}
+	return resultBody, nil
+}
+
+func UpdateSli(req *UpdateSliRequest) (*SliResponse, error) {
+	c := clients.NewBlamelessClient()
+	payload, err := json.Marshal(&req)
+	if err != nil {
+		return &SliResponse{}, err
+	}
+	InvalidateSli(req.OrgId, req.Id)
+	resp, err := c.Post(c.SloService, "UpdateSLI", payload)
+	if err != nil {
+		return &SliResponse{}, err
+	}
+	var resultBody *SliResponse
+	if err := json.Unmarshal(resp, &resultBody); err != nil {
+		return &SliResponse{}, err
+	}
+	if resultBody != nil {
+		cacheSli(req.OrgId, resultBody.Sli)
+	}
+	return resultBody, nil
+}
+
+func DeleteSli(req *DeleteSliRequest) (*DeleteSliResponse, error) {
+	c := clients.NewBlamelessClient()
+	payload, err := json.Marshal(&req)
+	if err != nil {
+		return &DeleteSliResponse{}, err
+	}
+	InvalidateSli(req.OrgId, req.Id)
+	resp, err := c.Post(c.SloService, "DeleteSLI", payload)
+	if err != nil {
+		return &DeleteSliResponse{}, err
+	}
+	var resultBody *DeleteSliResponse
+	if err := json.Unmarshal(resp, &resultBody); err != nil {
+		return &DeleteSliResponse{}, err
+	}
 	return resultBody, nil
 }
 
+// ListSlis returns one page of SLIs, optionally filtered by service and SLI type
+func ListSlis(req *ListSlisRequest) (*ListSlisResponse, error) {
+	c := clients.NewBlamelessClient()
+	payload, err := json.Marshal(&req)
+	if err != nil {
+		return &ListSlisResponse{}, err
+	}
+	resp, err := c.Post(c.SloService, "ListSLIs", payload)
+	if err != nil {
+		return &ListSlisResponse{}, err
+	}
+	var resultBody *ListSlisResponse
+	if err := json.Unmarshal(resp, &resultBody); err != nil {
+		return &ListSlisResponse{}, err
+	}
+	return resultBody, nil
+}
+
+// ListAllSlis walks every page of ListSlis starting from req.Page
+func ListAllSlis(req *ListSlisRequest) ([]*SliBody, error) {
+	page := *req
+	if page.Page < 1 {
+		page.Page = 1
+	}
+	if page.PageSize < 1 {
+		page.PageSize = DefaultPageSize
+	}
+	slis := []*SliBody{}
+	for {
+		resp, err := ListSlis(&page)
+		if err != nil {
+			return nil, err
+		}
+		slis = append(slis, resp.Slis...)
+		if len(resp.Slis) < page.PageSize || (resp.Total > 0 && len(slis) >= resp.Total) {
+			return slis, nil
+		}
+		page.Page++
+	}
+}
+
 func (s *SliBody) GetSliType() (*SliTypeResponse, error) {
+	if sliType, ok := cachedSliType(s.SliTypeId); ok {
+		return &SliTypeResponse{SliType: sliType}, nil
+	}
 	c := clients.NewBlamelessClient()
 	request := &SliTypeRequest{
 		Id: s.SliTypeId,
@@ -137,5 +269,93 @@ func (s *SliBody) GetSliType() (*SliTypeResponse, error) {
 	if err := json.Unmarshal(resp, &bodyResponse); err != nil {
 		return &SliTypeResponse{}, err
 	}
+	if bodyResponse != nil {
+		cacheSliType(bodyResponse.SliType)
+	}
 	return bodyResponse, nil
 }
+
+// ListSliTypes fetches the catalog of SLI types from Blameless
+func ListSliTypes() (*ListSliTypesResponse, error) {
+	c := clients.NewBlamelessClient()
+	payload, err := json.Marshal(&ListSliTypesRequest{})
+	if err != nil {
+		return &ListSliTypesResponse{}, err
+	}
+	resp, err := c.Post(c.SloService, "ListSliTypes", payload)
+	if err != nil {
+		return &ListSliTypesResponse{}, err
+	}
+	var resultBody *ListSliTypesResponse
+	if err := json.Unmarshal(resp, &resultBody); err != nil {
+		return &ListSliTypesResponse{}, err
+	}
+	return resultBody, nil
+}
+
+// CachedSliTypes fetches the SLI type catalog, reusing it until the cache TTL expires
+func CachedSliTypes() ([]*SliTypeBody, error) {
+	initCaches()
+	return sliTypeCatalogCache.GetOrLoad(catalogKey, func() ([]*SliTypeBody, error) {
+		resp, err := ListSliTypes()
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.SliTypes) == 0 {
+			return nil, fmt.Errorf("blameless returned an empty SLI type catalog")
+		}
+		for _, st := range resp.SliTypes {
+			cacheSliType(st)
+		}
+		return resp.SliTypes, nil
+	})
+}
+
+// DecodeMetricPath parses the JSON encoded metric path stored on the SLI
+func (s *SliBody) DecodeMetricPath() (*MetricPath, error) {
+	metricPath := &MetricPath{}
+	if s.MetricPath == "" {
+		return metricPath, nil
+	}
+	if err := json.Unmarshal([]byte(s.MetricPath), metricPath); err != nil {
+		return nil, fmt.Errorf("unable to decode metric path of sli %d: %v", s.Id, err)
+	}
+	return metricPath, nil
+}
+
+// Validate checks the metric path holds exactly the queries the SLI type reads
+func (m *MetricPath) Validate(sliTypeName string) error {
+	queries := map[string]string{
+		Types.Latency:     m.Latency,
+		Types.Throughput:  m.Throughput,
+		Types.Saturation:  m.Saturation,
+		Types.Correctness: m.Correctness,
+		Types.Durability:  m.Durability,
+	}
+	if sliTypeName == Types.Availability {
+		if m.Availability == nil || m.Availability.GoodRequest == "" || m.Availability.ValidRequest == "" {
+			return fmt.Errorf("an availability metric path needs goodRequest and validRequest queries")
+		}
+		for name, q := range queries {
+			if q != "" {
+				return fmt.Errorf("an availability metric path can not set a %s query", strings.ToLower(name))
+			}
+		}
+		return nil
+	}
+	if _, ok := queries[sliTypeName]; !ok {
+		return fmt.Errorf("SLI type %s has no metric path", sliTypeName)
+	}
+	if queries[sliTypeName] == "" {
+		return fmt.Errorf("a %s metric path needs a %s query", strings.ToLower(sliTypeName), strings.ToLower(sliTypeName))
+	}
+	if m.Availability != nil {
+		return fmt.Errorf("a %s metric path can not set availability queries", strings.ToLower(sliTypeName))
+	}
+	for name, q := range queries {
+		if name != sliTypeName && q != "" {
+			return fmt.Errorf("a %s metric path can not set a %s query", strings.ToLower(sliTypeName), strings.ToLower(name))
+		}
+	}
+	return nil
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 
 	"github.com/blamelesshq/blameless-examples/slo/packages/clients"
 	"github.com/blamelesshq/blameless-examples/slo/packages/config"
@@ -20,6 +22,16 @@ type SliRawDataBody struct {
 	End          int `json:"end" binding:"required"`
 }
 
+// rawDataFields lists the SliRawDataBody fields each sliType may fill
+var rawDataFields = map[string][]string{
+	"latency":      {"latency"},
+	"availability": {"goodRequest", "validRequest"},
+	"throughput":   {"throughput"},
+	"saturation":   {"saturation"},
+	"correctness":  {"correctness"},
+	"durability":   {"durability"},
+}
+
 type PostManyRequest struct {
 	OrgId   int              `json:"orgId"`
 	SliType string           `json:"sliType"`
@@ -28,19 +40,68 @@ type PostManyRequest struct {
 
 type PostManyResponse struct {
 	SliRawData *[]SliRawDataBody `json:"sliRawData"`
+	Errors     []*BatchError     `json:"-"`
+}
+
+// GetManyRequest reads back the raw data stored for an SLI whose start is in [Start, End)
+type GetManyRequest struct {
+	OrgId int `json:"orgId"`
+	SliId int `json:"sliId"`
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+type GetManyResponse struct {
+	SliRawData []SliRawDataBody `json:"sliRawData"`
 }
 
 type SliRawData interface {
-	PostMany(c *clients.BlamelessClient, data *[]SliRawDataBody) (*PostManyResponse, error)
+	PostMany(c *clients.BlamelessClient, sliType string, data *[]SliRawDataBody) (*PostManyResponse, error)
 }
 
-func PostMany(c *clients.BlamelessClient, data []SliRawDataBody) (*PostManyResponse, error) {
-	payload := &PostManyRequest{
+// NewPostManyRequest builds a request for data of a single SLI type. The type may be
+// given as the SLI type name, e.g. Types.Availability, or left empty to derive it from the data.
+func NewPostManyRequest(sliType string, data []SliRawDataBody) (*PostManyRequest, error) {
+	if sliType == "" {
+		derived, err := DeriveSliType(data)
+		if err != nil {
+			return nil, err
+		}
+		sliType = derived
+	}
+	req := &PostManyRequest{
 		OrgId:   config.Environment().Blameless.OrgId,
-		SliType: "latency",
+		SliType: strings.ToLower(sliType),
 		RawData: data,
 	}
-	postBody, err := json.Marshal(payload)
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// PostMany splits data into batches within the configured limits and posts them concurrently.
+// The response holds what was stored, with per batch errors for anything that failed.
+func PostMany(c *clients.BlamelessClient, sliType string, data []SliRawDataBody) (*PostManyResponse, error) {
+	req, err := NewPostManyRequest(sliType, data)
+	if err != nil {
+		return &PostManyResponse{}, err
+	}
+	opts := DefaultBatchOptions()
+	batches, err := req.Split(opts)
+	if err != nil {
+		return &PostManyResponse{}, err
+	}
+	resp := PostBatches(c, batches, opts)
+	return resp, resp.Err()
+}
+
+// Post sends an already built request, the outbox replays stored requests through this
+func (r *PostManyRequest) Post(c *clients.BlamelessClient) (*PostManyResponse, error) {
+	if err := r.Validate(); err != nil {
+		return &PostManyResponse{}, err
+	}
+	postBody, err := json.Marshal(r)
 	if err != nil {
 		return &PostManyResponse{}, err
 	}
@@ -55,3 +116,207 @@ func PostMany(c *clients.BlamelessClient, data []SliRawDataBody) (*PostManyRespo
 
 	return resultBody, nil
 }
+
+// GetMany reads back stored raw data from the timeseries service
+func GetMany(req *GetManyRequest) (*GetManyResponse, error) {
+	c := clients.NewBlamelessClient()
+	payload, err := json.Marshal(&req)
+	if err != nil {
+		return &GetManyResponse{}, err
+	}
+	resp, err := c.Post(c.SloTimeseriesService, "SliRawDataGetMany", payload)
+	if err != nil {
+		return &GetManyResponse{}, err
+	}
+	var resultBody *GetManyResponse
+	if err := json.Unmarshal(resp, &resultBody); err != nil {
+		return &GetManyResponse{}, err
+	}
+	return resultBody, nil
+}
+
+// GetAllRawData reads back the raw data stored between req.Start and req.End a day at a
+// time, keeping responses small. Points the service returns outside the range are dropped.
+func GetAllRawData(req *GetManyRequest) ([]SliRawDataBody, error) {
+	const day = 24 * 60 * 60
+	data := []SliRawDataBody{}
+	for start := req.Start; start < req.End; start += day {
+		chunk := *req
+		chunk.Start = start
+		chunk.End = start + day
+		if chunk.End > req.End {
+			chunk.End = req.End
+		}
+		resp, err := GetMany(&chunk)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range resp.SliRawData {
+			if d.Start >= chunk.Start && d.Start < chunk.End {
+				data = append(data, d)
+			}
+		}
+	}
+	return data, nil
+}
+
+// Value returns the measurement field of the given json name
+func (d *SliRawDataBody) Value(field string) int {
+	switch field {
+	case "latency":
+		return d.Latency
+	case "goodRequest":
+		return d.GoodRequest
+	case "validRequest":
+		return d.ValidRequest
+	case "throughput":
+		return d.Throughput
+	case "saturation":
+		return d.Saturation
+	case "correctness":
+		return d.Correctness
+	case "durability":
+		return d.Durability
+	}
+	return 0
+}
+
+// RawDataFields lists the measurement fields an SLI type fills, by json name
+func RawDataFields(sliType string) []string {
+	return rawDataFields[strings.ToLower(sliType)]
+}
+
+// filled returns the json names of the measurement fields that carry a value
+func (d *SliRawDataBody) filled() []string {
+	fields := []string{}
+	values := []struct {
+		name  string
+		value int
+	}{
+		{"latency", d.Latency},
+		{"goodRequest", d.GoodRequest},
+		{"validRequest", d.ValidRequest},
+		{"throughput", d.Throughput},
+		{"saturation", d.Saturation},
+		{"correctness", d.Correctness},
+		{"durability", d.Durability},
+	}
+	for _, v := range values {
+		if v.value != 0 {
+			fields = append(fields, v.name)
+		}
+	}
+	return fields
+}
+
+// Validate checks the body only fills fields that belong to sliType. The fields of sliType
+// are always posted, so a zero is a measurement rather than a missing value.
+func (d *SliRawDataBody) Validate(sliType string) error {
+	allowed, ok := rawDataFields[strings.ToLower(sliType)]
+	if !ok {
+		return fmt.Errorf("unknown sli type %q", sliType)
+	}
+	for _, field := range d.filled() {
+		if !contains(allowed, field) {
+			return fmt.Errorf("sli %d raw data at %d sets %s which is not valid for %s", d.SliId, d.Start, field, sliType)
+		}
+	}
+	return nil
+}
+
+// rawDataPayload is a raw data point as it is posted, nil fields are left out
+type rawDataPayload struct {
+	SliId        int  `json:"sliId"`
+	Latency      *int `json:"latency,omitempty"`
+	ValidRequest *int `json:"validRequest,omitempty"`
+	GoodRequest  *int `json:"goodRequest,omitempty"`
+	Throughput   *int `json:"throughput,omitempty"`
+	Correctness  *int `json:"correctness,omitempty"`
+	Saturation   *int `json:"saturation,omitempty"`
+	Durability   *int `json:"durability,omitempty"`
+	Start        int  `json:"start"`
+	End          int  `json:"end"`
+}
+
+// payload keeps the fields of sliType even when they are zero, other fields only when set
+func (d *SliRawDataBody) payload(sliType string) rawDataPayload {
+	fields := RawDataFields(sliType)
+	value := func(field string, v int) *int {
+		if v == 0 && !contains(fields, field) {
+			return nil
+		}
+		return &v
+	}
+	return rawDataPayload{
+		SliId:        d.SliId,
+		Latency:      value("latency", d.Latency),
+		ValidRequest: value("validRequest", d.ValidRequest),
+		GoodRequest:  value("goodRequest", d.GoodRequest),
+		Throughput:   value("throughput", d.Throughput),
+		Correctness:  value("correctness", d.Correctness),
+		Saturation:   value("saturation", d.Saturation),
+		Durability:   value("durability", d.Durability),
+		Start:        d.Start,
+		End:          d.End,
+	}
+}
+
+// MarshalJSON always sends the fields of the request's SLI type. The omitempty tags of
+// SliRawDataBody would drop a zero latency, throughput or request count otherwise.
+func (r PostManyRequest) MarshalJSON() ([]byte, error) {
+	data := make([]rawDataPayload, len(r.RawData))
+	for i := range r.RawData {
+		data[i] = r.RawData[i].payload(r.SliType)
+	}
+	return json.Marshal(struct {
+		OrgId   int              `json:"orgId"`
+		SliType string           `json:"sliType"`
+		RawData []rawDataPayload `json:"rawData"`
+	}{r.OrgId, r.SliType, data})
+}
+
+// Validate rejects requests whose raw data does not match the request sliType
+func (r *PostManyRequest) Validate() error {
+	for i := range r.RawData {
+		if err := r.RawData[i].Validate(r.SliType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeriveSliType infers the sliType from the fields the data fills, mixed batches are rejected
+func DeriveSliType(data []SliRawDataBody) (string, error) {
+	derived := ""
+	for i := range data {
+		for _, field := range data[i].filled() {
+			sliType := fieldSliType(field)
+			if derived != "" && derived != sliType {
+				return "", fmt.Errorf("raw data mixes %s and %s values, post each sli type separately", derived, sliType)
+			}
+			derived = sliType
+		}
+	}
+	if derived == "" {
+		return "", fmt.Errorf("unable to derive sli type from raw data without values")
+	}
+	return derived, nil
+}
+
+func fieldSliType(field string) string {
+	for sliType, fields := range rawDataFields {
+		if contains(fields, field) {
+			return sliType
+		}
+	}
+	return ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSliRawDataBodyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		sliType string
+		data    SliRawDataBody
+		wantErr bool
+	}{
+		{name: "latency", sliType: "latency", data: SliRawDataBody{SliId: 3, Latency: 120}},
+		{name: "type name in any case", sliType: "Availability", data: SliRawDataBody{SliId: 3, GoodRequest: 9, ValidRequest: 10}},
+		{name: "zero measurement", sliType: "latency", data: SliRawDataBody{SliId: 3}},
+		{name: "zero good requests", sliType: "availability", data: SliRawDataBody{SliId: 3, ValidRequest: 10}},
+		{name: "field of another type", sliType: "latency", data: SliRawDataBody{SliId: 3, Latency: 120, Throughput: 5}, wantErr: true},
+		{name: "unknown type", sliType: "freshness", data: SliRawDataBody{SliId: 3, Latency: 120}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.data.Validate(tt.sliType); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeriveSliType(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []SliRawDataBody
+		want    string
+		wantErr bool
+	}{
+		{name: "latency", data: []SliRawDataBody{{Latency: 120}, {Latency: 80}}, want: "latency"},
+		{name: "availability", data: []SliRawDataBody{{GoodRequest: 9, ValidRequest: 10}}, want: "availability"},
+		{name: "zeros are skipped", data: []SliRawDataBody{{}, {Throughput: 5}}, want: "throughput"},
+		{name: "mixed types", data: []SliRawDataBody{{Latency: 120}, {Saturation: 40}}, wantErr: true},
+		{name: "no values", data: []SliRawDataBody{{}, {}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DeriveSliType(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DeriveSliType() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("DeriveSliType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostManyRequestSendsZeroMeasurements(t *testing.T) {
+	tests := []struct {
+		name    string
+		sliType string
+		data    SliRawDataBody
+		want    string
+	}{
+		{
+			name:    "zero latency",
+			sliType: "latency",
+			data:    SliRawDataBody{SliId: 3, Start: 0, End: 60},
+			want:    `{"sliId":3,"latency":0,"start":0,"end":60}`,
+		},
+		{
+			name:    "no good requests",
+			sliType: "availability",
+			data:    SliRawDataBody{SliId: 3, ValidRequest: 10, Start: 60, End: 120},
+			want:    `{"sliId":3,"validRequest":10,"goodRequest":0,"start":60,"end":120}`,
+		},
+		{
+			name:    "no requests",
+			sliType: "availability",
+			data:    SliRawDataBody{SliId: 3, Start: 120, End: 180},
+			want:    `{"sliId":3,"validRequest":0,"goodRequest":0,"start":120,"end":180}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(&PostManyRequest{OrgId: 1, SliType: tt.sliType, RawData: []SliRawDataBody{tt.data}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := `{"orgId":1,"sliType":"` + tt.sliType + `","rawData":[` + tt.want + `]}`
+			if string(b) != want {
+				t.Errorf("json.Marshal() = %s, want %s", b, want)
+			}
+
+			var decoded PostManyRequest
+			if err := json.Unmarshal(b, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if decoded.RawData[0] != tt.data {
+				t.Errorf("decoded raw data = %+v, want %+v", decoded.RawData[0], tt.data)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/clients"
+)
+
+var WindowTypes = &SloWindowTypes{
+	Rolling:  "rolling",
+	Calendar: "calendar",
+}
+
+var CalendarUnits = []string{"week", "month", "quarter"}
+
+// Blameless only keeps 28 days of raw data, a rolling window can not be longer
+var MaxRollingDays = 28
+
+type SloWindowTypes struct {
+	Rolling  string
+	Calendar string
+}
+
+type SloWindow struct {
+	Type string `json:"type" yaml:"type"`
+	Days int    `json:"days,omitempty" yaml:"days,omitempty"` // Length of a rolling window
+	Unit string `json:"unit,omitempty" yaml:"unit,omitempty"` // week, month or quarter for a calendar window
+}
+
+type SloBody struct {
+	OrgId                int        `json:"orgId,omitempty"`
+	Id                   int        `json:"id,omitempty"`
+	Name                 string     `json:"name"`
+	Description          string     `json:"description"`
+	SliId                int        `json:"sliId"`
+	ObjectivePercentage  float64    `json:"objectivePercentage"`
+	Window               *SloWindow `json:"window"`
+	Threshold            float64    `json:"threshold,omitempty"` // Latency SLIs only, the latency a good event stays under
+	ErrorBudgetPolicyIds []int      `json:"errorBudgetPolicyIds,omitempty"`
+	UserId               int        `json:"userId,omitempty"`
+}
+
+type PostSloRequest struct {
+	OrgId int      `json:"orgId" binding:"required"`
+	Model *SloBody `json:"model" binding:"required"`
+}
+
+type GetSloRequest struct {
+	OrgId int `json:"orgId" binding:"required"`
+	Id    int `json:"id" binding:"required"`
+}
+
+type UpdateSloRequest struct {
+	OrgId int      `json:"orgId" binding:"required"`
+	Id    int      `json:"id" binding:"required"`
+	Model *SloBody `json:"model" binding:"required"`
+}
+
+type DeleteSloRequest struct {
+	OrgId int `json:"orgId" binding:"required"`
+	Id    int `json:"id" binding:"required"`
+}
+
+type DeleteSloResponse struct {
+	Success bool `json:"success"`
+}
+
+type ListSlosRequest struct {
+	OrgId    int `json:"orgId" binding:"required"`
+	Page     int `json:"page,omitempty"`
+	PageSize int `json:"pageSize,omitempty"`
+	SliId    int `json:"sliId,omitempty"`
+}
+
+type SloResponse struct {
+	Slo *SloBody `json:"slo"`
+}
+
+type ListSlosResponse struct {
+	Slos  []*SloBody `json:"slos"`
+	Total int        `json:"total"`
+}
+
+type Slo interface {
+	GetSlo(req *GetSloRequest) (*SloResponse, error)
+	PostSlo(req *PostSloRequest) (*SloResponse, error)
+	UpdateSlo(req *UpdateSloRequest) (*SloResponse, error)
+	DeleteSlo(req *DeleteSloRequest) (*DeleteSloResponse, error)
+	ListSlos(req *ListSlosRequest) (*ListSlosResponse, error)
+}
+
+func (w *SloWindow) String() string {
+	if w == nil {
+		return ""
+	}
+	if w.Type == WindowTypes.Calendar {
+		return fmt.Sprintf("calendar %s", w.Unit)
+	}
+	return fmt.Sprintf("rolling %dd", w.Days)
+}
+
+// Validate checks the SLO against the type of the SLI it is built on
+func (s *SloBody) Validate(sliTypeName string) error {
+	if s.Name == "" {
+		return fmt.Errorf("an SLO needs a name")
+	}
+	if s.ObjectivePercentage <= 0 || s.ObjectivePercentage >= 100 {
+		return fmt.Errorf("objective percentage must be between 0 and 100, got %g", s.ObjectivePercentage)
+	}
+	if s.Window == nil {
+		return fmt.Errorf("an SLO window is required")
+	}
+	switch s.Window.Type {
+	case WindowTypes.Rolling:
+		if s.Window.Days < 1 || s.Window.Days > MaxRollingDays {
+			return fmt.Errorf("rolling window must be between 1 and %d days, got %d", MaxRollingDays, s.Window.Days)
+		}
+	case WindowTypes.Calendar:
+		if !contains(CalendarUnits, s.Window.Unit) {
+			return fmt.Errorf("calendar window unit must be one of %s, got %q", strings.Join(CalendarUnits, ", "), s.Window.Unit)
+		}
+	default:
+		return fmt.Errorf("window type must be %s or %s, got %q", WindowTypes.Rolling, WindowTypes.Calendar, s.Window.Type)
+	}
+	if strings.EqualFold(sliTypeName, Types.Latency) {
+		if s.Threshold <= 0 {
+			return fmt.Errorf("a latency SLO needs a threshold above 0")
+		}
+	} else if s.Threshold != 0 {
+		return fmt.Errorf("a threshold only applies to latency SLOs, not %s", sliTypeName)
+	}
+	return nil
+}
+
+func GetSlo(req *GetSloRequest) (*SloResponse, error) {
+	c := clients.NewBlamelessClient()
+	payload, err := json.Marshal(&req)
+	if err != nil {
+		return &SloResponse{}, err
+	}
+	resp, err := c.Post(c.SloService, "GetSLO", payload)
+	if err != nil {
+		return &SloResponse{}, err
+	}
+	var resultBody *SloResponse
+	if err := json.Unmarshal(resp, &resultBody); err != nil {
+		return &SloResponse{}, err
+	}
+	return resultBody, nil
+}
+
+func PostSlo(req *PostSloRequest) (*SloResponse, error) {
+	c := clients.NewBlamelessClient()
+	payload, err := json.Marshal(&req)
+	if err != nil {
+		return &SloResponse{}, err
+	}
+	resp, err := c.Post(c.SloService, "CreateSLO", payload)
+	if err != nil {
+		return &SloResponse{}, err
+	}
+	var resultBody *SloResponse
+	if err := json.Unmarshal(resp, &resultBody); err != nil {
+		return &SloResponse{}, err
+	}
+	return resultBody, nil
+}
+
+func UpdateSlo(req *UpdateSloRequest) (*SloResponse, error) {
+	c := clients.NewBlamelessClient()
+	payload, err := json.Marshal(&req)
+	if err != nil {
+		return &SloResponse{}, err
+	}
+	resp, err := c.Post(c.SloService, "UpdateSLO", payload)
+	if err != nil {
+		return &SloResponse{}, err
+	}
+	var resultBody *SloResponse
+	if err := json.Unmarshal(resp, &resultBody); err != nil {
+		return &SloResponse{}, err
+	}
+	return resultBody, nil
+}
+
+func DeleteSlo(req *DeleteSloRequest) (*DeleteSloResponse, error) {
+	c := clients.NewBlamelessClient()
+	payload, err := json.Marshal(&req)
+	if err != nil {
+		return &DeleteSloResponse{}, err
+	}
+	resp, err := c.Post(c.SloService, "DeleteSLO", payload)
+	if err != nil {
+		return &DeleteSloResponse{}, err
+	}
+	var resultBody *DeleteSloResponse
+	if err := json.Unmarshal(resp, &resultBody); err != nil {
+		return &DeleteSloResponse{}, err
+	}
+	return resultBody, nil
+}
+
+// ListSlos returns one page of SLOs, optionally only those built on one SLI
+func ListSlos(req *ListSlosRequest) (*ListSlosResponse, error) {
+	c := clients.NewBlamelessClient()
+	payload, err := json.Marshal(&req)
+	if err != nil {
+		return &ListSlosResponse{}, err
+	}
+	resp, err := c.Post(c.SloService, "ListSLOs", payload)
+	if err != nil {
+		return &ListSlosResponse{}, err
+	}
+	var resultBody *ListSlosResponse
+	if err := json.Unmarshal(resp, &resultBody); err != nil {
+		return &ListSlosResponse{}, err
+	}
+	return resultBody, nil
+}
+
+// ListAllSlos walks every page of ListSlos starting from req.Page
+func ListAllSlos(req *ListSlosRequest) ([]*SloBody, error) {
+	page := *req
+	if page.Page < 1 {
+		page.Page = 1
+	}
+	if page.PageSize < 1 {
+		page.PageSize = DefaultPageSize
+	}
+	slos := []*SloBody{}
+	for {
+		resp, err := ListSlos(&page)
+		if err != nil {
+			return nil, err
+		}
+		slos = append(slos, resp.Slos...)
+		if len(resp.Slos) < page.PageSize || (resp.Total > 0 && len(slos) >= resp.Total) {
+			return slos, nil
+		}
+		page.Page++
+	}
+}
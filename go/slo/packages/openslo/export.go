@@ -0,0 +1,156 @@
+package openslo
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+)
+
+// DataSourceName is the metadata name of the Prometheus DataSource exported SLIs refer to
+const DataSourceName = "prometheus"
+
+// calendarStart anchors exported calendar windows, OpenSLO requires a start time
+var calendarStart = &Calendar{StartTime: "2024-01-01 00:00:00", TimeZone: "UTC"}
+
+func DataSource(url string) *Document {
+	return &Document{
+		ApiVersion: ApiVersion,
+		Kind:       Kinds.DataSource,
+		Metadata:   Metadata{Name: DataSourceName},
+		Spec: &DataSourceSpec{
+			Type:              PrometheusType,
+			ConnectionDetails: map[string]string{"url": url},
+		},
+	}
+}
+
+// ServiceName is the OpenSLO service of a Blameless service ID, ServiceMap reads it back
+func ServiceName(serviceId int) string {
+	return "service-" + strconv.Itoa(serviceId)
+}
+
+// Names keeps the metadata names of one export unique per kind. Blameless names that map
+// onto the same metadata name, such as "API Latency" and "api-latency", get their ID as a suffix.
+type Names struct {
+	taken map[string]map[string]bool
+}
+
+func NewNames() *Names {
+	return &Names{taken: map[string]map[string]bool{}}
+}
+
+// Export translates an SLI and the SLOs built on it into an SLI document followed by
+// one SLO document per SLO. Only availability and latency SLIs have an OpenSLO form.
+// Metadata names are taken from names, which is shared by every SLI of an export.
+func Export(sli *models.SliBody, sliType string, slos []*models.SloBody, names *Names) ([]*Document, error) {
+	mp, err := sli.DecodeMetricPath()
+	if err != nil {
+		return nil, err
+	}
+	spec := &SliSpec{Description: sli.Description}
+	switch sliType {
+	case models.Types.Availability:
+		if mp.Availability == nil {
+			return nil, fmt.Errorf("availability SLI %q has no good and valid queries", sli.Name)
+		}
+		spec.RatioMetric = &RatioMetric{
+			Counter: true,
+			Good:    prometheusMetric(mp.Availability.GoodRequest),
+			Total:   prometheusMetric(mp.Availability.ValidRequest),
+		}
+	case models.Types.Latency:
+		spec.ThresholdMetric = prometheusMetric(mp.Latency)
+	default:
+		return nil, fmt.Errorf("%s SLI %q has no OpenSLO form, only availability and latency SLIs are exported", sliType, sli.Name)
+	}
+
+	sliMetadata, err := names.metadata(Kinds.Sli, sli.Name, sli.Id)
+	if err != nil {
+		return nil, err
+	}
+	docs := []*Document{{
+		ApiVersion: ApiVersion,
+		Kind:       Kinds.Sli,
+		Metadata:   sliMetadata,
+		Spec:       spec,
+	}}
+	for _, slo := range slos {
+		sloMetadata, err := names.metadata(Kinds.Slo, slo.Name, slo.Id)
+		if err != nil {
+			return nil, err
+		}
+		objective := Objective{
+			DisplayName: slo.Name,
+			Target:      math.Round(slo.ObjectivePercentage*1e6) / 1e8,
+		}
+		if sliType == models.Types.Latency {
+			objective.Op = "lte"
+			objective.Value = slo.Threshold
+		}
+		docs = append(docs, &Document{
+			ApiVersion: ApiVersion,
+			Kind:       Kinds.Slo,
+			Metadata:   sloMetadata,
+			Spec: &SloSpec{
+				Description:     slo.Description,
+				Service:         ServiceName(sli.ServiceId),
+				IndicatorRef:    sliMetadata.Name,
+				TimeWindow:      []TimeWindow{exportWindow(slo.Window)},
+				BudgetingMethod: "Occurrences",
+				Objectives:      []Objective{objective},
+			},
+		})
+	}
+	return docs, nil
+}
+
+// metadata names a document of kind, suffixing the Blameless id when the name of another
+// document of the kind maps to the same metadata name. The Blameless name is kept as
+// displayName when it is not the metadata name.
+func (n *Names) metadata(kind string, name string, id int) (Metadata, error) {
+	taken, ok := n.taken[kind]
+	if !ok {
+		taken = map[string]bool{}
+		n.taken[kind] = taken
+	}
+	m := Metadata{Name: Name(name)}
+	if taken[m.Name] {
+		suffix := "-" + strconv.Itoa(id)
+		base := m.Name
+		if len(base)+len(suffix) > 63 {
+			base = strings.TrimRight(base[:63-len(suffix)], "-")
+		}
+		m.Name = base + suffix
+	}
+	if taken[m.Name] {
+		return Metadata{}, fmt.Errorf("%s %q maps to the metadata name %q of another %s", kind, name, m.Name, kind)
+	}
+	taken[m.Name] = true
+	if m.Name != name {
+		m.DisplayName = name
+	}
+	return m, nil
+}
+
+func prometheusMetric(query string) *MetricHolder {
+	return &MetricHolder{MetricSource: MetricSource{
+		MetricSourceRef: DataSourceName,
+		Type:            PrometheusType,
+		Spec:            map[string]interface{}{"query": query},
+	}}
+}
+
+func exportWindow(w *models.SloWindow) TimeWindow {
+	if w == nil || w.Type != models.WindowTypes.Calendar {
+		days := models.MaxRollingDays
+		if w != nil && w.Days > 0 {
+			days = w.Days
+		}
+		return TimeWindow{Duration: fmt.Sprintf("%dd", days), IsRolling: true}
+	}
+	units := map[string]string{"week": "1w", "month": "1M", "quarter": "1Q"}
+	return TimeWindow{Duration: units[w.Unit], Calendar: calendarStart}
+}
@@ -0,0 +1,271 @@
+package openslo
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/manifest"
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+)
+
+// ServiceResolver maps the service of an OpenSLO SLO to a Blameless service ID
+type ServiceResolver func(service string) (int, error)
+
+var exportedService = regexp.MustCompile(`^service-(\d+)$`)
+
+// ServiceMap resolves services through ids, then names written by Export such as
+// service-12, then fallback. A fallback of 0 makes unknown services an error.
+func ServiceMap(ids map[string]int, fallback int) ServiceResolver {
+	return func(service string) (int, error) {
+		if id, ok := ids[service]; ok {
+			return id, nil
+		}
+		if m := exportedService.FindStringSubmatch(service); m != nil {
+			return strconv.Atoi(m[1])
+		}
+		if fallback != 0 {
+			return fallback, nil
+		}
+		return 0, fmt.Errorf("no Blameless service ID for OpenSLO service %q, map it with --service %s=<id>", service, service)
+	}
+}
+
+type indicator struct {
+	metadata Metadata
+	spec     *SliSpec
+}
+
+// Import translates OpenSLO documents into a manifest. Ratio metrics become availability
+// SLIs, threshold metrics latency SLIs with the objective value as the SLO threshold.
+// An SLO with several objectives becomes one Blameless SLO per objective.
+func Import(docs []*Document, services ServiceResolver) (*manifest.Manifest, error) {
+	dataSources := map[string]string{}
+	indicators := map[string]*indicator{}
+	for _, doc := range docs {
+		switch spec := doc.Spec.(type) {
+		case *DataSourceSpec:
+			dataSources[doc.Metadata.Name] = spec.Type
+		case *SliSpec:
+			indicators[doc.Metadata.Name] = &indicator{metadata: doc.Metadata, spec: spec}
+		}
+	}
+
+	m := &manifest.Manifest{}
+	slis := map[string]*manifest.Sli{}
+	referenced := map[string]bool{}
+	for _, doc := range docs {
+		spec, ok := doc.Spec.(*SloSpec)
+		if !ok {
+			continue
+		}
+		ind, err := sloIndicator(doc, spec, indicators)
+		if err != nil {
+			return nil, err
+		}
+		referenced[ind.metadata.Name] = true
+		serviceId, err := services(spec.Service)
+		if err != nil {
+			return nil, err
+		}
+
+		sli, ok := slis[ind.metadata.Name]
+		if !ok {
+			sli, err = importSli(ind, serviceId, dataSources)
+			if err != nil {
+				return nil, err
+			}
+			slis[ind.metadata.Name] = sli
+			m.Slis = append(m.Slis, sli)
+		} else if sli.ServiceId != serviceId {
+			return nil, fmt.Errorf("SLI %q is used by SLOs of different services, Blameless SLIs belong to one service", ind.metadata.Name)
+		}
+
+		slos, err := importSlos(doc, spec, sli.Type)
+		if err != nil {
+			return nil, err
+		}
+		sli.Slos = append(sli.Slos, slos...)
+	}
+
+	// SLIs no SLO refers to have no service of their own
+	for _, doc := range docs {
+		if _, ok := doc.Spec.(*SliSpec); !ok || referenced[doc.Metadata.Name] {
+			continue
+		}
+		name, ind := doc.Metadata.Name, indicators[doc.Metadata.Name]
+		serviceId, err := services("")
+		if err != nil {
+			return nil, fmt.Errorf("SLI %q is not used by any SLO: %v", name, err)
+		}
+		sli, err := importSli(ind, serviceId, dataSources)
+		if err != nil {
+			return nil, err
+		}
+		m.Slis = append(m.Slis, sli)
+	}
+	return m, nil
+}
+
+func sloIndicator(doc *Document, spec *SloSpec, indicators map[string]*indicator) (*indicator, error) {
+	if spec.Indicator != nil {
+		return &indicator{metadata: spec.Indicator.Metadata, spec: &spec.Indicator.Spec}, nil
+	}
+	if ind, ok := indicators[spec.IndicatorRef]; ok {
+		return ind, nil
+	}
+	return nil, fmt.Errorf("SLO %q refers to SLI %q which is not in the documents", doc.Metadata.Name, spec.IndicatorRef)
+}
+
+func importSli(ind *indicator, serviceId int, dataSources map[string]string) (*manifest.Sli, error) {
+	sli := &manifest.Sli{
+		Name:        ind.metadata.displayName(),
+		Description: ind.spec.Description,
+		ServiceId:   serviceId,
+	}
+	query := func(h *MetricHolder) (string, error) {
+		return prometheusQuery(ind.metadata.Name, h, dataSources)
+	}
+
+	switch {
+	case ind.spec.RatioMetric != nil:
+		ratio := ind.spec.RatioMetric
+		if ratio.Total == nil {
+			return nil, fmt.Errorf("SLI %q has a ratio metric without a total", ind.metadata.Name)
+		}
+		total, err := query(ratio.Total)
+		if err != nil {
+			return nil, err
+		}
+		var good string
+		switch {
+		case ratio.Good != nil:
+			good, err = query(ratio.Good)
+		case ratio.Bad != nil:
+			var bad string
+			bad, err = query(ratio.Bad)
+			good = fmt.Sprintf("(%s) - (%s)", total, bad)
+		default:
+			err = fmt.Errorf("SLI %q has a ratio metric without good or bad", ind.metadata.Name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		sli.Type = models.Types.Availability
+		sli.MetricPath = &models.MetricPath{
+			Availability: &models.AvailabilityStruct{GoodRequest: good, ValidRequest: total},
+		}
+	case ind.spec.ThresholdMetric != nil:
+		latency, err := query(ind.spec.ThresholdMetric)
+		if err != nil {
+			return nil, err
+		}
+		sli.Type = models.Types.Latency
+		sli.MetricPath = &models.MetricPath{Latency: latency}
+	default:
+		return nil, fmt.Errorf("SLI %q needs a ratioMetric or thresholdMetric", ind.metadata.Name)
+	}
+	return sli, nil
+}
+
+// prometheusQuery reads the query of a metric source, which must be Prometheus
+// either directly or through the DataSource it refers to
+func prometheusQuery(sli string, h *MetricHolder, dataSources map[string]string) (string, error) {
+	source := h.MetricSource
+	sourceType := source.Type
+	if sourceType == "" && source.MetricSourceRef != "" {
+		t, ok := dataSources[source.MetricSourceRef]
+		if !ok {
+			return "", fmt.Errorf("SLI %q refers to data source %q which is not in the documents", sli, source.MetricSourceRef)
+		}
+		sourceType = t
+	}
+	if !strings.EqualFold(sourceType, PrometheusType) {
+		return "", fmt.Errorf("SLI %q reads from %q, only %s metric sources can be imported", sli, sourceType, PrometheusType)
+	}
+	query, _ := source.Spec["query"].(string)
+	if query == "" {
+		return "", fmt.Errorf("SLI %q has a metric source without a query", sli)
+	}
+	return query, nil
+}
+
+func importSlos(doc *Document, spec *SloSpec, sliType string) ([]*manifest.Slo, error) {
+	if len(spec.TimeWindow) != 1 {
+		return nil, fmt.Errorf("SLO %q needs exactly one timeWindow", doc.Metadata.Name)
+	}
+	window, err := importWindow(spec.TimeWindow[0])
+	if err != nil {
+		return nil, fmt.Errorf("SLO %q: %v", doc.Metadata.Name, err)
+	}
+	if len(spec.Objectives) == 0 {
+		return nil, fmt.Errorf("SLO %q has no objectives", doc.Metadata.Name)
+	}
+
+	slos := []*manifest.Slo{}
+	for i, o := range spec.Objectives {
+		name := doc.Metadata.displayName()
+		if len(spec.Objectives) > 1 {
+			if o.DisplayName != "" {
+				name = fmt.Sprintf("%s %s", name, o.DisplayName)
+			} else {
+				name = fmt.Sprintf("%s %d", name, i+1)
+			}
+		}
+		objective := o.TargetPercent
+		if objective == 0 {
+			objective = o.Target * 100
+		}
+		slo := &manifest.Slo{
+			Name:                name,
+			Description:         spec.Description,
+			ObjectivePercentage: math.Round(objective*1e6) / 1e6,
+			Window:              window,
+		}
+		if sliType == models.Types.Latency {
+			if o.Op != "" && o.Op != "lt" && o.Op != "lte" {
+				return nil, fmt.Errorf("SLO %q compares latency with %q, only lt and lte map to a Blameless threshold", doc.Metadata.Name, o.Op)
+			}
+			slo.Threshold = o.Value
+		}
+		slos = append(slos, slo)
+	}
+	return slos, nil
+}
+
+var durationPattern = regexp.MustCompile(`^(\d+)([mhdwMQY])$`)
+
+func importWindow(w TimeWindow) (*models.SloWindow, error) {
+	m := durationPattern.FindStringSubmatch(w.Duration)
+	if m == nil {
+		return nil, fmt.Errorf("invalid timeWindow duration %q", w.Duration)
+	}
+	n, _ := strconv.Atoi(m[1])
+	unit := m[2]
+
+	if !w.IsRolling {
+		units := map[string]string{"w": "week", "M": "month", "Q": "quarter"}
+		if n != 1 || units[unit] == "" {
+			return nil, fmt.Errorf("calendar window %s is not supported, use 1w, 1M or 1Q", w.Duration)
+		}
+		return &models.SloWindow{Type: models.WindowTypes.Calendar, Unit: units[unit]}, nil
+	}
+
+	days := 0
+	switch unit {
+	case "d":
+		days = n
+	case "w":
+		days = n * 7
+	case "h":
+		if n%24 == 0 {
+			days = n / 24
+		}
+	}
+	if days == 0 {
+		return nil, fmt.Errorf("rolling window %s is not a whole number of days", w.Duration)
+	}
+	return &models.SloWindow{Type: models.WindowTypes.Rolling, Days: days}, nil
+}
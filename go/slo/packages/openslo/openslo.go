@@ -0,0 +1,191 @@
+package openslo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const ApiVersion = "openslo/v1"
+
+var Kinds = &DocumentKinds{
+	Slo:        "SLO",
+	Sli:        "SLI",
+	DataSource: "DataSource",
+	Service:    "Service",
+}
+
+type DocumentKinds struct {
+	Slo        string
+	Sli        string
+	DataSource string
+	Service    string
+}
+
+// PrometheusType is the only metric source type queries are read from
+const PrometheusType = "Prometheus"
+
+type Metadata struct {
+	Name        string `yaml:"name"`
+	DisplayName string `yaml:"displayName,omitempty"`
+}
+
+// Document is one OpenSLO YAML document, Spec holds the spec struct of its kind
+type Document struct {
+	ApiVersion string      `yaml:"apiVersion"`
+	Kind       string      `yaml:"kind"`
+	Metadata   Metadata    `yaml:"metadata"`
+	Spec       interface{} `yaml:"spec"`
+}
+
+type rawDocument struct {
+	ApiVersion string    `yaml:"apiVersion"`
+	Kind       string    `yaml:"kind"`
+	Metadata   Metadata  `yaml:"metadata"`
+	Spec       yaml.Node `yaml:"spec"`
+}
+
+type DataSourceSpec struct {
+	Description       string            `yaml:"description,omitempty"`
+	Type              string            `yaml:"type"`
+	ConnectionDetails map[string]string `yaml:"connectionDetails,omitempty"`
+}
+
+type ServiceSpec struct {
+	Description string `yaml:"description,omitempty"`
+}
+
+type SliSpec struct {
+	Description     string        `yaml:"description,omitempty"`
+	ThresholdMetric *MetricHolder `yaml:"thresholdMetric,omitempty"`
+	RatioMetric     *RatioMetric  `yaml:"ratioMetric,omitempty"`
+}
+
+type RatioMetric struct {
+	Counter bool          `yaml:"counter"`
+	Good    *MetricHolder `yaml:"good,omitempty"`
+	Bad     *MetricHolder `yaml:"bad,omitempty"`
+	Total   *MetricHolder `yaml:"total"`
+}
+
+type MetricHolder struct {
+	MetricSource MetricSource `yaml:"metricSource"`
+}
+
+type MetricSource struct {
+	MetricSourceRef string                 `yaml:"metricSourceRef,omitempty"`
+	Type            string                 `yaml:"type,omitempty"`
+	Spec            map[string]interface{} `yaml:"spec"`
+}
+
+// InlineSli is an SLI declared inside an SLO instead of referenced by name
+type InlineSli struct {
+	Metadata Metadata `yaml:"metadata"`
+	Spec     SliSpec  `yaml:"spec"`
+}
+
+type SloSpec struct {
+	Description     string       `yaml:"description,omitempty"`
+	Service         string       `yaml:"service"`
+	Indicator       *InlineSli   `yaml:"indicator,omitempty"`
+	IndicatorRef    string       `yaml:"indicatorRef,omitempty"`
+	TimeWindow      []TimeWindow `yaml:"timeWindow"`
+	BudgetingMethod string       `yaml:"budgetingMethod"`
+	Objectives      []Objective  `yaml:"objectives"`
+}
+
+type TimeWindow struct {
+	Duration  string    `yaml:"duration"`
+	IsRolling bool      `yaml:"isRolling"`
+	Calendar  *Calendar `yaml:"calendar,omitempty"`
+}
+
+type Calendar struct {
+	StartTime string `yaml:"startTime"`
+	TimeZone  string `yaml:"timeZone"`
+}
+
+type Objective struct {
+	DisplayName   string  `yaml:"displayName,omitempty"`
+	Op            string  `yaml:"op,omitempty"`
+	Value         float64 `yaml:"value,omitempty"`
+	Target        float64 `yaml:"target,omitempty"`
+	TargetPercent float64 `yaml:"targetPercent,omitempty"`
+}
+
+// Parse reads every document of a multi document OpenSLO YAML stream
+func Parse(data []byte) ([]*Document, error) {
+	docs := []*Document{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for i := 0; ; i++ {
+		raw := &rawDocument{}
+		err := dec.Decode(raw)
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse document %d: %w", i, err)
+		}
+		if raw.Kind == "" {
+			continue
+		}
+		if raw.ApiVersion != ApiVersion {
+			return nil, fmt.Errorf("%s %q has apiVersion %q, only %s is supported", raw.Kind, raw.Metadata.Name, raw.ApiVersion, ApiVersion)
+		}
+
+		doc := &Document{ApiVersion: raw.ApiVersion, Kind: raw.Kind, Metadata: raw.Metadata}
+		switch raw.Kind {
+		case Kinds.Slo:
+			doc.Spec = &SloSpec{}
+		case Kinds.Sli:
+			doc.Spec = &SliSpec{}
+		case Kinds.DataSource:
+			doc.Spec = &DataSourceSpec{}
+		case Kinds.Service:
+			doc.Spec = &ServiceSpec{}
+		default:
+			// Alert policies and the like have no Blameless counterpart
+			continue
+		}
+		if err := raw.Spec.Decode(doc.Spec); err != nil {
+			return nil, fmt.Errorf("unable to parse %s %q: %w", raw.Kind, raw.Metadata.Name, err)
+		}
+		docs = append(docs, doc)
+	}
+}
+
+// Write encodes the documents as one YAML stream separated by ---
+func Write(w io.Writer, docs []*Document) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// Name turns a Blameless name into an OpenSLO metadata name, a lowercase DNS label
+func Name(name string) string {
+	n := invalidNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	n = strings.Trim(n, "-")
+	if len(n) > 63 {
+		n = strings.TrimRight(n[:63], "-")
+	}
+	return n
+}
+
+func (m Metadata) displayName() string {
+	if m.DisplayName != "" {
+		return m.DisplayName
+	}
+	return m.Name
+}
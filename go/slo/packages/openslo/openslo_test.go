@@ -0,0 +1,146 @@
+package openslo
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/manifest"
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+)
+
+type exported struct {
+	sli     *models.SliBody
+	sliType string
+	slos    []*models.SloBody
+}
+
+var availabilityPath = `{"availability":{"goodRequest":"sum(rate(http_requests_total{code!~\"5..\"}[5m]))","validRequest":"sum(rate(http_requests_total[5m]))"}}`
+
+// blamelessSlis ends with two latency SLIs whose names, and those of their SLOs, map to the same metadata name
+var blamelessSlis = []exported{
+	{
+		sli:     &models.SliBody{Id: 10, Name: "Checkout Availability", Description: "checkout requests", ServiceId: 7, MetricPath: availabilityPath},
+		sliType: models.Types.Availability,
+		slos: []*models.SloBody{
+			{Id: 20, Name: "monthly", Description: "three nines", ObjectivePercentage: 99.9, Window: &models.SloWindow{Type: models.WindowTypes.Calendar, Unit: "month"}},
+			{Id: 21, Name: "rolling", ObjectivePercentage: 99.5, Window: &models.SloWindow{Type: models.WindowTypes.Rolling, Days: 28}},
+		},
+	},
+	{
+		sli:     &models.SliBody{Id: 11, Name: "api-latency", ServiceId: 8, MetricPath: `{"latency":"histogram_quantile(0.99, rate(latency_bucket[5m]))"}`},
+		sliType: models.Types.Latency,
+		slos:    []*models.SloBody{{Id: 30, Name: "p99 300ms", ObjectivePercentage: 99, Threshold: 300, Window: &models.SloWindow{Type: models.WindowTypes.Rolling, Days: 7}}},
+	},
+	{
+		sli:     &models.SliBody{Id: 12, Name: "API Latency", ServiceId: 8, MetricPath: `{"latency":"histogram_quantile(0.9, rate(latency_bucket[5m]))"}`},
+		sliType: models.Types.Latency,
+		slos:    []*models.SloBody{{Id: 31, Name: "p99 300ms", ObjectivePercentage: 95, Threshold: 300, Window: &models.SloWindow{Type: models.WindowTypes.Rolling, Days: 7}}},
+	},
+}
+
+func exportAll(t *testing.T) []*Document {
+	t.Helper()
+	docs := []*Document{DataSource("http://prometheus:9090")}
+	names := NewNames()
+	for _, e := range blamelessSlis {
+		exported, err := Export(e.sli, e.sliType, e.slos, names)
+		if err != nil {
+			t.Fatalf("Export(%q) error = %v", e.sli.Name, err)
+		}
+		docs = append(docs, exported...)
+	}
+	return docs
+}
+
+func TestExportNames(t *testing.T) {
+	got := map[string][]string{}
+	for _, doc := range exportAll(t) {
+		got[doc.Kind] = append(got[doc.Kind], doc.Metadata.Name)
+	}
+	want := map[string][]string{
+		Kinds.DataSource: {"prometheus"},
+		Kinds.Sli:        {"checkout-availability", "api-latency", "api-latency-12"},
+		Kinds.Slo:        {"monthly", "rolling", "p99-300ms", "p99-300ms-31"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("metadata names = %v, want %v", got, want)
+	}
+}
+
+func TestNamesMetadata(t *testing.T) {
+	long := "a very long service level indicator name that does not fit into sixty three characters"
+	tests := []struct {
+		name    string
+		taken   []string
+		id      int
+		want    Metadata
+		wantErr bool
+	}{
+		{name: "api-latency", id: 1, want: Metadata{Name: "api-latency"}},
+		{name: "API Latency", id: 2, want: Metadata{Name: "api-latency", DisplayName: "API Latency"}},
+		{name: "API Latency", taken: []string{"api-latency"}, id: 2, want: Metadata{Name: "api-latency-2", DisplayName: "API Latency"}},
+		{name: long, taken: []string{Name(long)}, id: 1234, want: Metadata{Name: "a-very-long-service-level-indicator-name-that-does-not-fit-1234", DisplayName: long}},
+		{name: "API Latency", taken: []string{"api-latency", "api-latency-2"}, id: 2, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := NewNames()
+			for _, n := range tt.taken {
+				names.metadata(Kinds.Sli, n, 0)
+			}
+			// Names of another kind never collide
+			names.metadata(Kinds.Slo, tt.name, 0)
+
+			got, err := names.metadata(Kinds.Sli, tt.name, tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("metadata() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("metadata() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	var out bytes.Buffer
+	if err := Write(&out, exportAll(t)); err != nil {
+		t.Fatal(err)
+	}
+	docs, err := Parse(out.Bytes())
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	got, err := Import(docs, ServiceMap(nil, 0))
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	want := &manifest.Manifest{}
+	for _, e := range blamelessSlis {
+		mp, err := e.sli.DecodeMetricPath()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sli := &manifest.Sli{Name: e.sli.Name, Description: e.sli.Description, ServiceId: e.sli.ServiceId, Type: e.sliType, MetricPath: mp}
+		for _, s := range e.slos {
+			sli.Slos = append(sli.Slos, &manifest.Slo{
+				Name:                s.Name,
+				Description:         s.Description,
+				ObjectivePercentage: s.ObjectivePercentage,
+				Window:              s.Window,
+				Threshold:           s.Threshold,
+			})
+		}
+		want.Slis = append(want.Slis, sli)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Import(Export()) differs\n%s", out.String())
+		for i := range want.Slis {
+			if i < len(got.Slis) && !reflect.DeepEqual(got.Slis[i], want.Slis[i]) {
+				t.Errorf("sli %d = %+v, want %+v", i, got.Slis[i], want.Slis[i])
+			}
+		}
+	}
+}
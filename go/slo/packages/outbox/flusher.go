@@ -0,0 +1,44 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/clients"
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+)
+
+// BlamelessSender delivers replayed batches through the Blameless client
+func BlamelessSender(c *clients.BlamelessClient) Sender {
+	return func(req *models.PostManyRequest) error {
+		_, err := req.Post(c)
+		return err
+	}
+}
+
+// Flush replays queued batches every interval until ctx is done. Failures are logged
+// and left queued for the next tick.
+func (o *Outbox) Flush(ctx context.Context, interval time.Duration, send Sender) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			delivered, err := o.Replay(send)
+			if delivered > 0 {
+				slog.Info("outbox flushed", "delivered", delivered, "dir", o.dir)
+			}
+			if err != nil {
+				slog.Warn("outbox flush incomplete", "delivered", delivered, "error", err)
+			}
+		}
+	}
+}
+
+// StartFlusher runs Flush in the background on the configured interval
+func (o *Outbox) StartFlusher(ctx context.Context, interval time.Duration, send Sender) {
+	go o.Flush(ctx, interval, send)
+}
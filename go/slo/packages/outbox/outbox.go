@@ -0,0 +1,266 @@
+package outbox
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/config"
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+)
+
+const batchExt = ".json"
+
+// ErrQueued wraps delivery errors whose data was kept in the outbox
+var ErrQueued = errors.New("queued in outbox")
+
+var outboxOncer sync.Once
+var defaultOutbox *Outbox
+var defaultErr error
+
+// Batch is a PostManyRequest that could not be delivered, kept until it is replayed or dropped
+type Batch struct {
+	Id        string                  `json:"id"`
+	Hash      string                  `json:"hash"`
+	Created   time.Time               `json:"created"`
+	Attempts  int                     `json:"attempts"`
+	LastError string                  `json:"lastError,omitempty"`
+	Request   *models.PostManyRequest `json:"request"`
+}
+
+// Outbox is a write ahead store of undelivered batches. Each batch is its own file,
+// named so that lexical order is enqueue order, and written with an atomic rename so
+// a crash never leaves a half written batch behind.
+type Outbox struct {
+	dir string
+	mu  sync.Mutex
+}
+
+type Sender func(req *models.PostManyRequest) error
+
+type OutboxClient interface {
+	Enqueue(req *models.PostManyRequest, cause error) (*Batch, error)
+	List() ([]*Batch, error)
+	Get(id string) (*Batch, error)
+	Drop(id string) error
+	Replay(send Sender, ids ...string) (int, error)
+}
+
+// Open creates the outbox directory if needed
+func Open(dir string) (*Outbox, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create outbox %s: %v", dir, err)
+	}
+	return &Outbox{dir: dir}, nil
+}
+
+// Default opens the outbox configured by outbox.dir
+func Default() (*Outbox, error) {
+	outboxOncer.Do(func() {
+		defaultOutbox, defaultErr = Open(config.Environment().Outbox.Dir)
+	})
+	return defaultOutbox, defaultErr
+}
+
+// Enqueue stores req durably. A batch with identical content that is already queued is returned instead of stored twice.
+func (o *Outbox) Enqueue(req *models.PostManyRequest, cause error) (*Batch, error) {
+	hash, err := hashRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	batches, err := o.list()
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range batches {
+		if b.Hash == hash {
+			return b, nil
+		}
+	}
+
+	now := time.Now().UTC()
+	batch := &Batch{
+		Id:      fmt.Sprintf("%020d-%s", now.UnixNano(), hash[:12]),
+		Hash:    hash,
+		Created: now,
+		Request: req,
+	}
+	if cause != nil {
+		batch.LastError = cause.Error()
+	}
+	if err := o.write(batch); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// List returns queued batches oldest first
+func (o *Outbox) List() ([]*Batch, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.list()
+}
+
+func (o *Outbox) Get(id string) (*Batch, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.read(o.path(id))
+}
+
+func (o *Outbox) Drop(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := os.Remove(o.path(id)); err != nil {
+		return fmt.Errorf("unable to drop batch %s: %v", id, err)
+	}
+	return nil
+}
+
+// Replay sends queued batches oldest first, or only the given ids, removing each once
+// delivered. It stops at the first failure so later data is never sent ahead of earlier data.
+func (o *Outbox) Replay(send Sender, ids ...string) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	batches, err := o.list()
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) > 0 {
+		batches, err = selectBatches(batches, ids)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	delivered := 0
+	sent := map[string]bool{}
+	for _, b := range batches {
+		if !sent[b.Hash] {
+			if err := send(b.Request); err != nil {
+				b.Attempts++
+				b.LastError = err.Error()
+				if werr := o.write(b); werr != nil {
+					return delivered, werr
+				}
+				return delivered, fmt.Errorf("replay of batch %s failed: %v", b.Id, err)
+			}
+			sent[b.Hash] = true
+		}
+		if err := os.Remove(o.path(b.Id)); err != nil {
+			return delivered, fmt.Errorf("batch %s was delivered but could not be removed: %v", b.Id, err)
+		}
+		delivered++
+	}
+	return delivered, nil
+}
+
+func selectBatches(batches []*Batch, ids []string) ([]*Batch, error) {
+	byId := make(map[string]*Batch, len(batches))
+	for _, b := range batches {
+		byId[b.Id] = b
+	}
+	selected := make([]*Batch, 0, len(ids))
+	for _, id := range ids {
+		b, ok := byId[id]
+		if !ok {
+			return nil, fmt.Errorf("batch %s is not queued", id)
+		}
+		selected = append(selected, b)
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Id < selected[j].Id })
+	return selected, nil
+}
+
+func (o *Outbox) list() ([]*Batch, error) {
+	entries, err := os.ReadDir(o.dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read outbox %s: %v", o.dir, err)
+	}
+	batches := []*Batch{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), batchExt) {
+			continue
+		}
+		b, err := o.read(filepath.Join(o.dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, b)
+	}
+	// ReadDir is sorted by name and names start with the enqueue time
+	return batches, nil
+}
+
+func (o *Outbox) read(path string) (*Batch, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read batch %s: %v", filepath.Base(path), err)
+	}
+	var batch *Batch
+	if err := json.Unmarshal(b, &batch); err != nil {
+		return nil, fmt.Errorf("unable to decode batch %s: %v", filepath.Base(path), err)
+	}
+	return batch, nil
+}
+
+func (o *Outbox) write(batch *Batch) error {
+	b, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(o.dir, ".batch-*")
+	if err != nil {
+		return fmt.Errorf("unable to write batch %s: %v", batch.Id, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to write batch %s: %v", batch.Id, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to sync batch %s: %v", batch.Id, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), o.path(batch.Id)); err != nil {
+		return fmt.Errorf("unable to commit batch %s: %v", batch.Id, err)
+	}
+	return syncDir(o.dir)
+}
+
+func (o *Outbox) path(id string) string {
+	return filepath.Join(o.dir, filepath.Base(id)+batchExt)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func hashRequest(req *models.PostManyRequest) (string, error) {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
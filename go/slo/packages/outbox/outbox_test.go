@@ -0,0 +1,156 @@
+package outbox
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+)
+
+func request(start int) *models.PostManyRequest {
+	return &models.PostManyRequest{
+		OrgId:   1,
+		SliType: "latency",
+		RawData: []models.SliRawDataBody{{SliId: 3, Start: start, End: start + 60, Latency: 120}},
+	}
+}
+
+func open(t *testing.T) *Outbox {
+	t.Helper()
+	o, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func enqueue(t *testing.T, o *Outbox, starts ...int) []*Batch {
+	t.Helper()
+	batches := []*Batch{}
+	for _, start := range starts {
+		b, err := o.Enqueue(request(start), errors.New("503 Service Unavailable"))
+		if err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+		batches = append(batches, b)
+	}
+	return batches
+}
+
+func TestEnqueue(t *testing.T) {
+	o := open(t)
+	queued := enqueue(t, o, 0, 60, 0)
+	if queued[2].Id != queued[0].Id {
+		t.Errorf("identical request queued as %s, want the existing batch %s", queued[2].Id, queued[0].Id)
+	}
+
+	batches, err := o.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 2 || batches[0].Id != queued[0].Id || batches[1].Id != queued[1].Id {
+		t.Fatalf("List() = %+v, want the two batches oldest first", batches)
+	}
+	if batches[0].LastError != "503 Service Unavailable" || batches[0].Attempts != 0 {
+		t.Errorf("batch = %+v, want the cause recorded and no attempts", batches[0])
+	}
+	got, err := o.Get(queued[1].Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Request, request(60)) {
+		t.Errorf("Get() request = %+v, want %+v", got.Request, request(60))
+	}
+}
+
+func TestReplay(t *testing.T) {
+	tests := []struct {
+		name      string
+		fail      int // Start of the raw data the sender rejects, -1 for none
+		ids       []int
+		delivered int
+		sent      []int
+		remaining int
+		wantErr   bool
+	}{
+		{name: "every batch", fail: -1, delivered: 3, sent: []int{0, 60, 120}},
+		{name: "stops at the first failure", fail: 60, delivered: 1, sent: []int{0, 60}, remaining: 2, wantErr: true},
+		{name: "selected batches", fail: -1, ids: []int{2, 0}, delivered: 2, sent: []int{0, 120}, remaining: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := open(t)
+			queued := enqueue(t, o, 0, 60, 120)
+			sent := []int{}
+			send := func(req *models.PostManyRequest) error {
+				start := req.RawData[0].Start
+				sent = append(sent, start)
+				if start == tt.fail {
+					return errors.New("502 Bad Gateway")
+				}
+				return nil
+			}
+			ids := []string{}
+			for _, i := range tt.ids {
+				ids = append(ids, queued[i].Id)
+			}
+
+			delivered, err := o.Replay(send, ids...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Replay() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if delivered != tt.delivered || !reflect.DeepEqual(sent, tt.sent) {
+				t.Errorf("Replay() delivered %d sending %v, want %d sending %v", delivered, sent, tt.delivered, tt.sent)
+			}
+			batches, err := o.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(batches) != tt.remaining {
+				t.Fatalf("remaining batches = %d, want %d", len(batches), tt.remaining)
+			}
+			if tt.wantErr && (batches[0].Attempts != 1 || batches[0].LastError != "502 Bad Gateway") {
+				t.Errorf("failed batch = %+v, want the attempt and error recorded", batches[0])
+			}
+		})
+	}
+}
+
+func TestReplaySendsCopiesOnce(t *testing.T) {
+	o := open(t)
+	queued := enqueue(t, o, 0)
+	// A batch restored from a backup carries the same content under another id
+	restored := *queued[0]
+	restored.Id += "-restored"
+	if err := o.write(&restored); err != nil {
+		t.Fatal(err)
+	}
+
+	sends := 0
+	delivered, err := o.Replay(func(req *models.PostManyRequest) error {
+		sends++
+		return nil
+	})
+	if err != nil || delivered != 2 || sends != 1 {
+		t.Errorf("Replay() = %d, %v with %d sends, want both batches removed after one send", delivered, err, sends)
+	}
+}
+
+func TestDrop(t *testing.T) {
+	o := open(t)
+	queued := enqueue(t, o, 0, 60)
+	if err := o.Drop(queued[0].Id); err != nil {
+		t.Fatalf("Drop() error = %v", err)
+	}
+	if err := o.Drop(queued[0].Id); err == nil {
+		t.Error("Drop() of a dropped batch error = nil, want an error")
+	}
+	if _, err := o.Replay(func(*models.PostManyRequest) error { return nil }, queued[0].Id); err == nil {
+		t.Error("Replay() of a dropped batch error = nil, want an error")
+	}
+	batches, err := o.List()
+	if err != nil || len(batches) != 1 || batches[0].Id != queued[1].Id {
+		t.Errorf("List() = %+v, %v, want only %s", batches, err, queued[1].Id)
+	}
+}
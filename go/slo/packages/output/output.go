@@ -0,0 +1,226 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/cheynewallace/tabby"
+	"gopkg.in/yaml.v3"
+)
+
+var Formats = &OutputFormats{
+	Table:    "table",
+	JSON:     "json",
+	YAML:     "yaml",
+	CSV:      "csv",
+	Template: "template",
+}
+
+type OutputFormats struct {
+	Table    string
+	JSON     string
+	YAML     string
+	CSV      string
+	Template string
+}
+
+// Table is the tabular view of a result, printed by the table and csv formats
+type Table struct {
+	Headers []string
+	Rows    [][]interface{}
+}
+
+func NewTable(headers ...string) *Table {
+	return &Table{Headers: headers, Rows: [][]interface{}{}}
+}
+
+func (t *Table) AddRow(values ...interface{}) {
+	t.Rows = append(t.Rows, values)
+}
+
+// Validate checks every row has one value per header
+func (t *Table) Validate() error {
+	for i, row := range t.Rows {
+		if len(row) != len(t.Headers) {
+			return fmt.Errorf("table row %d has %d values for %d headers %v", i, len(row), len(t.Headers), t.Headers)
+		}
+	}
+	return nil
+}
+
+// Printer renders command results in one output format. The json, yaml and template
+// formats print the result itself, table and csv print its Table.
+type Printer struct {
+	format   string
+	template *template.Template
+	w        io.Writer
+}
+
+// New parses an output spec, one of table, json, yaml, csv or template=<go template>.
+// Templates run against the result as it is printed in JSON, so field names match jq.
+func New(spec string, w io.Writer) (*Printer, error) {
+	p := &Printer{format: spec, w: w}
+	switch spec {
+	case Formats.Table, Formats.JSON, Formats.YAML, Formats.CSV:
+		return p, nil
+	}
+	if text, ok := strings.CutPrefix(spec, Formats.Template+"="); ok {
+		tmpl, err := template.New("output").Funcs(template.FuncMap{"json": toJSON}).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output template: %w", err)
+		}
+		p.format = Formats.Template
+		p.template = tmpl
+		return p, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q, use table, json, yaml, csv or template=<go template>", spec)
+}
+
+func (p *Printer) Print(data interface{}, table *Table) error {
+	switch p.format {
+	case Formats.JSON:
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(data)
+	case Formats.YAML:
+		generic, err := toGeneric(data)
+		if err != nil {
+			return err
+		}
+		enc := yaml.NewEncoder(p.w)
+		enc.SetIndent(2)
+		if err := enc.Encode(generic); err != nil {
+			return err
+		}
+		return enc.Close()
+	case Formats.Template:
+		generic, err := toGeneric(data)
+		if err != nil {
+			return err
+		}
+		return p.template.Execute(p.w, generic)
+	case Formats.CSV:
+		return p.printCSV(table)
+	}
+	return p.printTable(table)
+}
+
+func (p *Printer) printTable(table *Table) error {
+	if err := table.Validate(); err != nil {
+		return err
+	}
+	t := tabby.NewCustom(tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0))
+	headers := make([]interface{}, len(table.Headers))
+	for i, h := range table.Headers {
+		headers[i] = h
+	}
+	t.AddHeader(headers...)
+	for _, row := range table.Rows {
+		t.AddLine(row...)
+	}
+	t.Print()
+	return nil
+}
+
+func (p *Printer) printCSV(table *Table) error {
+	if err := table.Validate(); err != nil {
+		return err
+	}
+	w := csv.NewWriter(p.w)
+	if err := w.Write(table.Headers); err != nil {
+		return err
+	}
+	for _, row := range table.Rows {
+		record := make([]string, len(row))
+		for i, v := range row {
+			record[i] = fmt.Sprint(v)
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// toGeneric round trips data through JSON so yaml and templates see the JSON field names.
+// Whole numbers stay integers rather than becoming floats such as 1e+06.
+func toGeneric(data interface{}) (interface{}, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return numbers(generic), nil
+}
+
+func numbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = numbers(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = numbers(e)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
+
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline draws values as a line of block characters scaled between their min and
+// max, NaN values are drawn as spaces. At most width values are drawn, evenly picked.
+func Sparkline(values []float64, width int) string {
+	if width > 0 && len(values) > width {
+		picked := make([]float64, width)
+		for i := range picked {
+			picked[i] = values[i*len(values)/width]
+		}
+		values = picked
+	}
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+	}
+	line := make([]rune, len(values))
+	for i, v := range values {
+		switch {
+		case math.IsNaN(v) || math.IsInf(v, 0):
+			line[i] = ' '
+		case max == min:
+			line[i] = sparkBars[len(sparkBars)/2]
+		default:
+			line[i] = sparkBars[int((v-min)/(max-min)*float64(len(sparkBars)-1))]
+		}
+	}
+	return string(line)
+}
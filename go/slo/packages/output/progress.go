@@ -0,0 +1,86 @@
+package output
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-isatty"
+)
+
+// progressWidth is how many characters the bar itself takes
+const progressWidth = 30
+
+// progressLogInterval is how often progress is logged when stderr is not a terminal
+const progressLogInterval = 10 * time.Second
+
+// Progress draws a bar on stderr when it is a terminal, otherwise it logs the count
+// now and then so redirected output is not filled with redraws
+type Progress struct {
+	label   string
+	total   int
+	done    int
+	failed  int
+	started time.Time
+	logged  time.Time
+	tty     bool
+}
+
+func NewProgress(label string, total int) *Progress {
+	now := time.Now()
+	p := &Progress{label: label, total: total, started: now, logged: now, tty: isatty.IsTerminal(os.Stderr.Fd())}
+	p.draw()
+	return p
+}
+
+// Add counts a completed unit of work, failed ones are shown apart
+func (p *Progress) Add(failed bool) {
+	p.done++
+	if failed {
+		p.failed++
+	}
+	p.draw()
+}
+
+// Finish ends the bar line, further output starts on its own line
+func (p *Progress) Finish() {
+	if p.tty {
+		fmt.Fprintln(os.Stderr)
+		return
+	}
+	slog.Info(p.label, "done", p.done, "total", p.total, "failed", p.failed, "elapsed", time.Since(p.started).Round(time.Second).String())
+}
+
+func (p *Progress) draw() {
+	if !p.tty {
+		if time.Since(p.logged) >= progressLogInterval {
+			p.logged = time.Now()
+			slog.Info(p.label, "done", p.done, "total", p.total, "failed", p.failed)
+		}
+		return
+	}
+	filled := progressWidth
+	if p.total > 0 {
+		filled = p.done * progressWidth / p.total
+	}
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressWidth-filled)
+	line := fmt.Sprintf("\r%s [%s] %d/%d", p.label, bar, p.done, p.total)
+	if p.failed > 0 {
+		line += fmt.Sprintf(" %d failed", p.failed)
+	}
+	if eta := p.eta(); eta > 0 {
+		line += fmt.Sprintf(" eta %s", eta)
+	}
+	fmt.Fprint(os.Stderr, line+"\033[K")
+}
+
+// eta extrapolates the time left from the pace so far
+func (p *Progress) eta() time.Duration {
+	if p.done == 0 || p.done >= p.total {
+		return 0
+	}
+	elapsed := time.Since(p.started)
+	return (elapsed / time.Duration(p.done) * time.Duration(p.total-p.done)).Round(time.Second)
+}
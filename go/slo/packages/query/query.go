@@ -0,0 +1,98 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/clients"
+	"github.com/blamelesshq/blameless-examples/slo/packages/output"
+)
+
+// sparklineWidth is how many samples a sparkline draws at most
+const sparklineWidth = 40
+
+// Series summarizes one series a query returned
+type Series struct {
+	Labels    map[string]string `json:"labels"`
+	Points    int               `json:"points"`
+	NaN       int               `json:"nan"` // Samples without a value, ingest skips them
+	Min       float64           `json:"min"`
+	Max       float64           `json:"max"`
+	Last      float64           `json:"last"`
+	Sparkline string            `json:"sparkline"`
+	Samples   []Sample          `json:"samples"` // The latest samples
+}
+
+type Sample struct {
+	Time  int    `json:"time"`
+	Value string `json:"value"`
+}
+
+// Result is what a query returns over a recent range, as ingest would run it
+type Result struct {
+	Query  string    `json:"query"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Series []*Series `json:"series"`
+}
+
+// Test runs a query between from and to, keeping the latest samples of each series
+func Test(p *clients.PrometheusClient, query string, from time.Time, to time.Time, samples int) (*Result, error) {
+	series, err := p.QueryRangeSeries(query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	r := &Result{Query: query, From: from, To: to, Series: []*Series{}}
+	for _, s := range series {
+		summary := &Series{Labels: s.Labels, Points: len(s.Values), Samples: []Sample{}}
+		if summary.Labels == nil {
+			summary.Labels = map[string]string{}
+		}
+		for i := len(s.Values) - samples; i < len(s.Values); i++ {
+			if i >= 0 {
+				summary.Samples = append(summary.Samples, Sample{Time: s.Values[i].Time, Value: s.Values[i].Value})
+			}
+		}
+
+		values := make([]float64, len(s.Values))
+		first := true
+		for i, v := range s.Values {
+			f, err := strconv.ParseFloat(v.Value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("sample %q of query %q is not a number", v.Value, query)
+			}
+			values[i] = f
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				summary.NaN++
+				continue
+			}
+			if first || f < summary.Min {
+				summary.Min = f
+			}
+			if first || f > summary.Max {
+				summary.Max = f
+			}
+			first = false
+			summary.Last = f
+		}
+		summary.Sparkline = output.Sparkline(values, sparklineWidth)
+		r.Series = append(r.Series, summary)
+	}
+	return r, nil
+}
+
+// Validate rejects results ingest can not use, SLI queries must return exactly one series
+func (r *Result) Validate() error {
+	switch n := len(r.Series); {
+	case n == 0:
+		return fmt.Errorf("query %q returned no series since %s, check the metric name and label matchers", r.Query, r.From.Format(time.RFC3339))
+	case n > 1:
+		return fmt.Errorf("query %q returned %d series, aggregate it to one such as sum(...) as ingest only reads the first", r.Query, n)
+	}
+	if r.Series[0].Points == r.Series[0].NaN {
+		return fmt.Errorf("query %q returned no sample with a value", r.Query)
+	}
+	return nil
+}
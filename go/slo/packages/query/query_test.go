@@ -0,0 +1,131 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/clients"
+)
+
+// prometheus holds the series the fake Prometheus API of these tests returns by query
+var prometheus = map[string][]map[string]any{
+	"none": {},
+	"up": {
+		{"metric": map[string]string{"job": "api"}, "values": [][]any{{60, "1"}, {120, "NaN"}, {180, "0.5"}, {240, "2"}}},
+	},
+	"by_instance": {
+		{"metric": map[string]string{"instance": "a"}, "values": [][]any{{60, "1"}}},
+		{"metric": map[string]string{"instance": "b"}, "values": [][]any{{60, "2"}}},
+	},
+	"stale": {
+		{"metric": map[string]string{}, "values": [][]any{{60, "NaN"}, {120, "+Inf"}}},
+	},
+}
+
+func servePrometheus(w http.ResponseWriter, r *http.Request) {
+	series, ok := prometheus[r.URL.Query().Get("query")]
+	if !ok {
+		http.Error(w, `{"status":"error","errorType":"bad_data","error":"unknown query"}`, http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"status": "success", "data": map[string]any{"resultType": "matrix", "result": series}})
+}
+
+// TestMain points config at the fake Prometheus API, config is read from the working directory
+func TestMain(m *testing.M) {
+	srv := httptest.NewServer(http.HandlerFunc(servePrometheus))
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		panic(err)
+	}
+	dir, err := os.MkdirTemp("", "query")
+	if err != nil {
+		panic(err)
+	}
+	cfg := fmt.Sprintf(`prometheus:
+  host: "http://%s"
+  port: %s
+ingest:
+  step: 60
+http:
+  retry:
+    count: 0
+`, u.Hostname(), u.Port())
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(cfg), 0o600); err != nil {
+		panic(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	srv.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func test(t *testing.T, query string) *Result {
+	t.Helper()
+	r, err := Test(clients.NewPrometheusClient(), query, time.Unix(0, 0), time.Unix(300, 0), 2)
+	if err != nil {
+		t.Fatalf("Test(%q) error = %v", query, err)
+	}
+	return r
+}
+
+func TestTest(t *testing.T) {
+	r := test(t, "up")
+	if len(r.Series) != 1 {
+		t.Fatalf("Series = %d, want 1", len(r.Series))
+	}
+	got := *r.Series[0]
+	got.Sparkline = ""
+	want := Series{
+		Labels:  map[string]string{"job": "api"},
+		Points:  4,
+		NaN:     1,
+		Min:     0.5,
+		Max:     2,
+		Last:    2,
+		Samples: []Sample{{Time: 180, Value: "0.5"}, {Time: 240, Value: "2"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Series[0] = %+v, want %+v", got, want)
+	}
+	if r.Series[0].Sparkline == "" {
+		t.Error("Sparkline is empty")
+	}
+}
+
+func TestTestRejectedQuery(t *testing.T) {
+	if _, err := Test(clients.NewPrometheusClient(), "sum(", time.Unix(0, 0), time.Unix(300, 0), 2); err == nil {
+		t.Error("Test() error = nil, want the error Prometheus returned")
+	}
+}
+
+func TestResultValidate(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+	}{
+		{query: "up"},
+		{query: "none", wantErr: true},
+		{query: "by_instance", wantErr: true},
+		{query: "stale", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if err := test(t, tt.query).Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package reconcile
+
+import (
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+)
+
+// Gaps lists the step windows between from and to that no stored raw data starts in.
+// The windows are aligned to step, a window is covered by any point starting inside it
+// so data posted by regular ingest off the step grid still counts.
+func Gaps(stored []models.SliRawDataBody, from time.Time, to time.Time, step time.Duration) []Window {
+	size := int(step.Seconds())
+	if size <= 0 {
+		return []Window{}
+	}
+	start := int(from.Truncate(step).Unix())
+	end := int(to.Unix())
+
+	covered := map[int]bool{}
+	for _, d := range stored {
+		if d.Start >= start && d.Start < end {
+			covered[(d.Start-start)/size] = true
+		}
+	}
+
+	missing := []models.SliRawDataBody{}
+	for i, t := 0, start; t+size <= end; i, t = i+1, t+size {
+		if !covered[i] {
+			missing = append(missing, models.SliRawDataBody{Start: t, End: t + size})
+		}
+	}
+	return windows(missing)
+}
+
+// Coalesce merges gaps separated by no more than distance, so nearby gaps are filled
+// with one query instead of many
+func Coalesce(gaps []Window, distance time.Duration) []Window {
+	merged := []Window{}
+	for _, g := range gaps {
+		if n := len(merged); n > 0 && g.Start-merged[n-1].End <= int(distance.Seconds()) {
+			merged[n-1].End = g.End
+			merged[n-1].Points += g.Points
+			continue
+		}
+		merged = append(merged, g)
+	}
+	return merged
+}
+
+// Within keeps the raw data that starts inside one of the gaps
+func Within(data []models.SliRawDataBody, gaps []Window) []models.SliRawDataBody {
+	kept := []models.SliRawDataBody{}
+	for _, d := range data {
+		for _, g := range gaps {
+			if d.Start >= g.Start && d.Start < g.End {
+				kept = append(kept, d)
+				break
+			}
+		}
+	}
+	return kept
+}
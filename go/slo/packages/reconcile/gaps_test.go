@@ -0,0 +1,117 @@
+package reconcile
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+)
+
+func TestGaps(t *testing.T) {
+	tests := []struct {
+		name   string
+		stored []models.SliRawDataBody
+		from   time.Time
+		to     time.Time
+		step   time.Duration
+		want   []Window
+	}{
+		{
+			name:   "fully covered",
+			stored: []models.SliRawDataBody{latency(6000, 1), latency(6060, 1), latency(6120, 1)},
+			from:   time.Unix(6000, 0),
+			to:     time.Unix(6180, 0),
+			step:   time.Minute,
+		},
+		{
+			name: "nothing stored",
+			from: time.Unix(6000, 0),
+			to:   time.Unix(6180, 0),
+			step: time.Minute,
+			want: []Window{{Start: 6000, End: 6180, Points: 3}},
+		},
+		{
+			name:   "gaps between stored points",
+			stored: []models.SliRawDataBody{latency(6060, 1), latency(6240, 1)},
+			from:   time.Unix(6000, 0),
+			to:     time.Unix(6300, 0),
+			step:   time.Minute,
+			want:   []Window{{Start: 6000, End: 6060, Points: 1}, {Start: 6120, End: 6240, Points: 2}},
+		},
+		{
+			name:   "off grid points cover their window",
+			stored: []models.SliRawDataBody{latency(6017, 1), latency(6077, 1), latency(6137, 1)},
+			from:   time.Unix(6000, 0),
+			to:     time.Unix(6180, 0),
+			step:   time.Minute,
+		},
+		{
+			name:   "from is aligned to step",
+			stored: []models.SliRawDataBody{latency(6000, 1)},
+			from:   time.Unix(6030, 0),
+			to:     time.Unix(6180, 0),
+			step:   time.Minute,
+			want:   []Window{{Start: 6060, End: 6180, Points: 2}},
+		},
+		{
+			name: "a partial last window is left out",
+			from: time.Unix(6000, 0),
+			to:   time.Unix(6090, 0),
+			step: time.Minute,
+			want: []Window{{Start: 6000, End: 6060, Points: 1}},
+		},
+		{
+			name: "no step",
+			from: time.Unix(6000, 0),
+			to:   time.Unix(6180, 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check(t, "Gaps()", Gaps(tt.stored, tt.from, tt.to, tt.step), tt.want)
+		})
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	gaps := []Window{
+		{Start: 0, End: 60, Points: 1},
+		{Start: 120, End: 240, Points: 2},
+		{Start: 600, End: 660, Points: 1},
+	}
+	tests := []struct {
+		name     string
+		distance time.Duration
+		want     []Window
+	}{
+		{
+			name: "no distance keeps separate gaps",
+			want: gaps,
+		},
+		{
+			name:     "gaps within distance merge",
+			distance: time.Minute,
+			want:     []Window{{Start: 0, End: 240, Points: 3}, {Start: 600, End: 660, Points: 1}},
+		},
+		{
+			name:     "every gap within distance",
+			distance: 6 * time.Minute,
+			want:     []Window{{Start: 0, End: 660, Points: 4}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check(t, "Coalesce()", Coalesce(gaps, tt.distance), tt.want)
+		})
+	}
+}
+
+func TestWithin(t *testing.T) {
+	data := []models.SliRawDataBody{latency(0, 1), latency(60, 2), latency(180, 3), latency(240, 4)}
+	gaps := []Window{{Start: 60, End: 120, Points: 1}, {Start: 180, End: 240, Points: 1}}
+	want := []models.SliRawDataBody{latency(60, 2), latency(180, 3)}
+	if got := Within(data, gaps); !reflect.DeepEqual(got, want) {
+		t.Errorf("Within() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,188 @@
+package reconcile
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+)
+
+// Window is a run of consecutive step buckets, [Start, End) in unix seconds
+type Window struct {
+	Start  int `json:"start"`
+	End    int `json:"end"`
+	Points int `json:"points"`
+}
+
+// Duplicate is a bucket stored more than once
+type Duplicate struct {
+	Start int `json:"start"`
+	Count int `json:"count"`
+}
+
+// Mismatch is a bucket whose stored value differs from what Prometheus returns now
+type Mismatch struct {
+	Start    int    `json:"start"`
+	Field    string `json:"field"`
+	Stored   int    `json:"stored"`
+	Expected int    `json:"expected"`
+}
+
+// Report compares the raw data stored in Blameless for an SLI with a fresh Prometheus
+// query over the same range, bucket by bucket. Buckets are the step windows of the
+// range, a point belongs to the bucket it starts in so data posted by regular ingest
+// off the step grid is matched too. Starts are those of the buckets.
+type Report struct {
+	SliId      int         `json:"sliId"`
+	From       time.Time   `json:"from"`
+	To         time.Time   `json:"to"`
+	Expected   int         `json:"expected"` // Buckets Prometheus returns
+	Stored     int         `json:"stored"`   // Buckets stored in Blameless, duplicates counted once
+	Missing    []Window    `json:"missing"`  // Buckets Prometheus returns that were never stored
+	Extra      []Window    `json:"extra"`    // Buckets stored that Prometheus no longer returns
+	Duplicates []Duplicate `json:"duplicates"`
+	Mismatches []Mismatch  `json:"mismatches"`
+
+	missing []models.SliRawDataBody
+}
+
+// Compare matches stored raw data with the expected raw data queried from Prometheus,
+// both bucketed by step from the start of the range. Values differing by no more than
+// tolerance, relative to the expected value, match.
+func Compare(sliId int, sliType string, from time.Time, to time.Time, step time.Duration, expected []models.SliRawDataBody, stored []models.SliRawDataBody, tolerance float64) *Report {
+	r := &Report{
+		SliId:      sliId,
+		From:       from,
+		To:         to,
+		Missing:    []Window{},
+		Extra:      []Window{},
+		Duplicates: []Duplicate{},
+		Mismatches: []Mismatch{},
+		missing:    []models.SliRawDataBody{},
+	}
+	b := newBuckets(from, step)
+
+	storedByBucket := b.group(stored)
+	r.Stored = len(storedByBucket)
+	for _, start := range sortedStarts(storedByBucket) {
+		if n := len(storedByBucket[start]); n > 1 {
+			r.Duplicates = append(r.Duplicates, Duplicate{Start: start, Count: n})
+		}
+	}
+
+	fields := models.RawDataFields(sliType)
+	expectedByBucket := b.group(expected)
+	r.Expected = len(expectedByBucket)
+	for _, start := range sortedStarts(expectedByBucket) {
+		e := expectedByBucket[start][0]
+		copies, ok := storedByBucket[start]
+		if !ok {
+			r.missing = append(r.missing, e)
+			continue
+		}
+		// Every copy of a duplicated bucket is compared, a field is reported once
+		for _, f := range fields {
+			for _, s := range copies {
+				if !within(s.Value(f), e.Value(f), tolerance) {
+					r.Mismatches = append(r.Mismatches, Mismatch{Start: start, Field: f, Stored: s.Value(f), Expected: e.Value(f)})
+					break
+				}
+			}
+		}
+	}
+	r.Missing = b.windows(r.missing)
+
+	extra := []models.SliRawDataBody{}
+	for _, start := range sortedStarts(storedByBucket) {
+		if _, ok := expectedByBucket[start]; !ok {
+			extra = append(extra, storedByBucket[start][0])
+		}
+	}
+	r.Extra = b.windows(extra)
+	return r
+}
+
+// Consistent reports whether every bucket is stored once with the expected values
+func (r *Report) Consistent() bool {
+	return len(r.Missing) == 0 && len(r.Extra) == 0 && len(r.Duplicates) == 0 && len(r.Mismatches) == 0
+}
+
+// MissingData returns the expected raw data of the missing buckets, ready to repost
+func (r *Report) MissingData() []models.SliRawDataBody {
+	return r.missing
+}
+
+// windows groups sorted buckets into runs where each bucket starts as the previous one ends
+func windows(data []models.SliRawDataBody) []Window {
+	runs := []Window{}
+	for _, d := range data {
+		if n := len(runs); n > 0 && runs[n-1].End == d.Start {
+			runs[n-1].End = d.End
+			runs[n-1].Points++
+			continue
+		}
+		runs = append(runs, Window{Start: d.Start, End: d.End, Points: 1})
+	}
+	return runs
+}
+
+func within(stored int, expected int, tolerance float64) bool {
+	if stored == expected {
+		return true
+	}
+	return math.Abs(float64(stored-expected)) <= tolerance*math.Abs(float64(expected))
+}
+
+// buckets are the step windows of a range, counted from its start aligned to the step
+type buckets struct {
+	start int
+	size  int
+}
+
+func newBuckets(from time.Time, step time.Duration) buckets {
+	size := int(step.Seconds())
+	if size <= 0 {
+		size = 1
+	}
+	return buckets{start: int(from.Truncate(step).Unix()), size: size}
+}
+
+// of returns the start of the bucket a point starting at t falls in
+func (b buckets) of(t int) int {
+	offset := t - b.start
+	if offset < 0 {
+		// Integer division rounds towards zero, points before the range go to earlier buckets
+		offset -= b.size - 1
+	}
+	return b.start + offset/b.size*b.size
+}
+
+// group keys raw data by the start of its bucket, in the order given
+func (b buckets) group(data []models.SliRawDataBody) map[int][]models.SliRawDataBody {
+	byBucket := map[int][]models.SliRawDataBody{}
+	for _, d := range data {
+		start := b.of(d.Start)
+		byBucket[start] = append(byBucket[start], d)
+	}
+	return byBucket
+}
+
+// windows groups the buckets of sorted raw data into runs of consecutive buckets
+func (b buckets) windows(data []models.SliRawDataBody) []Window {
+	aligned := make([]models.SliRawDataBody, len(data))
+	for i, d := range data {
+		start := b.of(d.Start)
+		aligned[i] = models.SliRawDataBody{Start: start, End: start + b.size}
+	}
+	return windows(aligned)
+}
+
+func sortedStarts(byStart map[int][]models.SliRawDataBody) []int {
+	starts := make([]int, 0, len(byStart))
+	for start := range byStart {
+		starts = append(starts, start)
+	}
+	sort.Ints(starts)
+	return starts
+}
@@ -0,0 +1,114 @@
+package reconcile
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+)
+
+func latency(start int, value int) models.SliRawDataBody {
+	return models.SliRawDataBody{SliId: 1, Start: start, End: start + 60, Latency: value}
+}
+
+func TestCompare(t *testing.T) {
+	from := time.Unix(6000, 0)
+	to := time.Unix(6300, 0)
+	tests := []struct {
+		name          string
+		expected      []models.SliRawDataBody
+		stored        []models.SliRawDataBody
+		tolerance     float64
+		consistent    bool
+		storedBuckets int
+		missing       []Window
+		extra         []Window
+		duplicates    []Duplicate
+		mismatches    []Mismatch
+	}{
+		{
+			name:          "all stored",
+			expected:      []models.SliRawDataBody{latency(6000, 10), latency(6060, 20)},
+			stored:        []models.SliRawDataBody{latency(6060, 20), latency(6000, 10)},
+			consistent:    true,
+			storedBuckets: 2,
+		},
+		{
+			name:          "off grid points match their bucket",
+			expected:      []models.SliRawDataBody{latency(6000, 10), latency(6060, 20)},
+			stored:        []models.SliRawDataBody{latency(6017, 10), latency(6077, 20)},
+			consistent:    true,
+			storedBuckets: 2,
+		},
+		{
+			name:          "missing runs",
+			expected:      []models.SliRawDataBody{latency(6000, 1), latency(6060, 1), latency(6120, 1), latency(6180, 1)},
+			stored:        []models.SliRawDataBody{latency(6060, 1)},
+			storedBuckets: 1,
+			missing:       []Window{{Start: 6000, End: 6060, Points: 1}, {Start: 6120, End: 6240, Points: 2}},
+		},
+		{
+			name:          "extra bucket",
+			expected:      []models.SliRawDataBody{latency(6000, 1)},
+			stored:        []models.SliRawDataBody{latency(6000, 1), latency(6130, 1)},
+			storedBuckets: 2,
+			extra:         []Window{{Start: 6120, End: 6180, Points: 1}},
+		},
+		{
+			name:          "duplicate with a differing copy",
+			expected:      []models.SliRawDataBody{latency(6000, 10)},
+			stored:        []models.SliRawDataBody{latency(6000, 10), latency(6030, 12)},
+			storedBuckets: 1,
+			duplicates:    []Duplicate{{Start: 6000, Count: 2}},
+			mismatches:    []Mismatch{{Start: 6000, Field: "latency", Stored: 12, Expected: 10}},
+		},
+		{
+			name:          "mismatch within tolerance",
+			expected:      []models.SliRawDataBody{latency(6000, 100)},
+			stored:        []models.SliRawDataBody{latency(6000, 101)},
+			tolerance:     0.01,
+			consistent:    true,
+			storedBuckets: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Compare(1, models.Types.Latency, from, to, time.Minute, tt.expected, tt.stored, tt.tolerance)
+			if r.Consistent() != tt.consistent {
+				t.Errorf("Consistent() = %v, want %v: %+v", r.Consistent(), tt.consistent, r)
+			}
+			if r.Stored != tt.storedBuckets {
+				t.Errorf("Stored = %d, want %d", r.Stored, tt.storedBuckets)
+			}
+			if r.Expected != len(tt.expected) {
+				t.Errorf("Expected = %d, want %d", r.Expected, len(tt.expected))
+			}
+			check(t, "Missing", r.Missing, tt.missing)
+			check(t, "Extra", r.Extra, tt.extra)
+			check(t, "Duplicates", r.Duplicates, tt.duplicates)
+			check(t, "Mismatches", r.Mismatches, tt.mismatches)
+		})
+	}
+}
+
+func TestCompareMissingData(t *testing.T) {
+	expected := []models.SliRawDataBody{latency(6000, 1), latency(6060, 2)}
+	stored := []models.SliRawDataBody{latency(6010, 1)}
+	r := Compare(1, models.Types.Latency, time.Unix(6000, 0), time.Unix(6120, 0), time.Minute, expected, stored, 0)
+	want := []models.SliRawDataBody{latency(6060, 2)}
+	if got := r.MissingData(); !reflect.DeepEqual(got, want) {
+		t.Errorf("MissingData() = %+v, want %+v", got, want)
+	}
+}
+
+// check compares a report slice with the wanted one, nil wanting an empty slice
+func check[T any](t *testing.T, name string, got []T, want []T) {
+	t.Helper()
+	if want == nil {
+		want = []T{}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s = %+v, want %+v", name, got, want)
+	}
+}
@@ -0,0 +1,196 @@
+package rules
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Windows are the ranges SLI error ratios are recorded over. 2h is the short window of
+// the 1d ticket alert in the SRE workbook, the rest are the page and ticket windows.
+var Windows = []string{"5m", "30m", "1h", "2h", "6h", "1d", "3d"}
+
+// BurnRateCondition fires when the error budget burns factor times faster than the
+// objective allows over both windows, the short one makes the alert reset quickly
+type BurnRateCondition struct {
+	Factor      float64
+	LongWindow  string
+	ShortWindow string
+}
+
+type BurnRateAlert struct {
+	Severity   string
+	Conditions []BurnRateCondition
+}
+
+// BurnRateAlerts are the multiwindow, multi-burn-rate alerts of the Google SRE workbook
+var BurnRateAlerts = []BurnRateAlert{
+	{Severity: "page", Conditions: []BurnRateCondition{
+		{Factor: 14.4, LongWindow: "1h", ShortWindow: "5m"}, // 2% of a 30 day budget in an hour
+		{Factor: 6, LongWindow: "6h", ShortWindow: "30m"},   // 5% in six hours
+	}},
+	{Severity: "ticket", Conditions: []BurnRateCondition{
+		{Factor: 3, LongWindow: "1d", ShortWindow: "2h"}, // 10% in a day
+		{Factor: 1, LongWindow: "3d", ShortWindow: "6h"}, // 10% in three days
+	}},
+}
+
+// RuleFile is a Prometheus rule file, see promtool check rules
+type RuleFile struct {
+	Groups []*RuleGroup `yaml:"groups"`
+}
+
+type RuleGroup struct {
+	Name  string  `yaml:"name"`
+	Rules []*Rule `yaml:"rules"`
+}
+
+type Rule struct {
+	Record      string            `yaml:"record,omitempty"`
+	Alert       string            `yaml:"alert,omitempty"`
+	Expr        string            `yaml:"expr"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+const (
+	errorRatioRecord  = "slo:sli_error:ratio_rate"
+	objectiveRecord   = "slo:objective:ratio"
+	errorBudgetRecord = "slo:error_budget:ratio"
+	burnRateAlertName = "SloErrorBudgetBurn"
+)
+
+var rangeSelector = regexp.MustCompile(`\[\s*\d+(ms|[smhdwy])\s*\]`)
+
+// Generate writes recording rules for the error ratio of each SLO of an SLI over every
+// window, and burn rate alerts on top of them. Availability ratios scale the range
+// selectors of the good and valid queries to each window. Latency ratios are the share
+// of resolution sized slices whose latency is above the SLO threshold.
+func Generate(sli *models.SliBody, sliType string, slos []*models.SloBody, resolution string) (*RuleFile, error) {
+	mp, err := sli.DecodeMetricPath()
+	if err != nil {
+		return nil, err
+	}
+	if sliType != models.Types.Availability && sliType != models.Types.Latency {
+		return nil, fmt.Errorf("%s SLI %q has no error ratio, rules are generated for availability and latency SLIs", sliType, sli.Name)
+	}
+	if len(slos) == 0 {
+		return nil, fmt.Errorf("SLI %q has no SLOs to alert on", sli.Name)
+	}
+
+	file := &RuleFile{}
+	for _, slo := range slos {
+		labels := map[string]string{
+			"sli_id":     strconv.Itoa(sli.Id),
+			"sli":        sli.Name,
+			"slo_id":     strconv.Itoa(slo.Id),
+			"slo":        slo.Name,
+			"service_id": strconv.Itoa(sli.ServiceId),
+		}
+		recordings := &RuleGroup{Name: fmt.Sprintf("slo-%d-recordings", slo.Id)}
+		for _, w := range Windows {
+			expr, err := errorRatio(sliType, mp, slo, w, resolution)
+			if err != nil {
+				return nil, fmt.Errorf("SLI %q: %v", sli.Name, err)
+			}
+			recordings.Rules = append(recordings.Rules, &Rule{Record: errorRatioRecord + w, Expr: expr, Labels: labels})
+		}
+		recordings.Rules = append(recordings.Rules,
+			&Rule{Record: objectiveRecord, Expr: formatFloat(round(slo.ObjectivePercentage / 100)), Labels: labels},
+			&Rule{Record: errorBudgetRecord, Expr: formatFloat(errorBudget(slo)), Labels: labels},
+		)
+
+		alerts := &RuleGroup{Name: fmt.Sprintf("slo-%d-alerts", slo.Id)}
+		for _, a := range BurnRateAlerts {
+			alerts.Rules = append(alerts.Rules, burnRateRule(a, slo, labels))
+		}
+		file.Groups = append(file.Groups, recordings, alerts)
+	}
+	return file, nil
+}
+
+func errorRatio(sliType string, mp *models.MetricPath, slo *models.SloBody, window string, resolution string) (string, error) {
+	if sliType == models.Types.Latency {
+		if mp.Latency == "" || slo.Threshold <= 0 {
+			return "", fmt.Errorf("latency SLO %q needs a query and a threshold", slo.Name)
+		}
+		return fmt.Sprintf("avg_over_time(((%s) > bool %s)[%s:%s])", mp.Latency, formatFloat(slo.Threshold), window, resolution), nil
+	}
+	if mp.Availability == nil {
+		return "", fmt.Errorf("availability SLI has no good and valid queries")
+	}
+	good, err := scale(mp.Availability.GoodRequest, window)
+	if err != nil {
+		return "", err
+	}
+	valid, err := scale(mp.Availability.ValidRequest, window)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("1 - ((%s) / (%s))", good, valid), nil
+}
+
+// scale replaces every range selector of a query with window
+func scale(query string, window string) (string, error) {
+	if !rangeSelector.MatchString(query) {
+		return "", fmt.Errorf("query %q has no range selector to scale to %s", query, window)
+	}
+	return rangeSelector.ReplaceAllLiteralString(query, "["+window+"]"), nil
+}
+
+func burnRateRule(a BurnRateAlert, slo *models.SloBody, labels map[string]string) *Rule {
+	selector := fmt.Sprintf(`{slo_id="%d"}`, slo.Id)
+	budget := formatFloat(errorBudget(slo))
+	expr := ""
+	for i, c := range a.Conditions {
+		if i > 0 {
+			expr += "\nor\n"
+		}
+		threshold := fmt.Sprintf("%s * %s", formatFloat(c.Factor), budget)
+		expr += fmt.Sprintf("(\n  %s%s%s > %s\n  and\n  %s%s%s > %s\n)",
+			errorRatioRecord, c.LongWindow, selector, threshold,
+			errorRatioRecord, c.ShortWindow, selector, threshold)
+	}
+
+	alertLabels := map[string]string{"severity": a.Severity}
+	for k, v := range labels {
+		alertLabels[k] = v
+	}
+	return &Rule{
+		Alert:  burnRateAlertName,
+		Expr:   expr,
+		Labels: alertLabels,
+		Annotations: map[string]string{
+			"summary":     fmt.Sprintf("SLO %s is burning its error budget too fast", slo.Name),
+			"description": fmt.Sprintf("The error rate of SLO %s (objective %s%%) would exhaust its error budget early, see Blameless for its budget.", slo.Name, formatFloat(slo.ObjectivePercentage)),
+		},
+	}
+}
+
+// errorBudget is the share of events an SLO may fail, 0.001 for 99.9%
+func errorBudget(slo *models.SloBody) float64 {
+	return round(1 - slo.ObjectivePercentage/100)
+}
+
+// round drops the float noise of percentage arithmetic such as 0.0010000000000000009
+func round(f float64) float64 {
+	return math.Round(f*1e9) / 1e9
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func Write(w io.Writer, file *RuleFile) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(file); err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,150 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+)
+
+const (
+	availabilityPath = `{"availability":{"goodRequest":"sum(rate(http_requests_total{code!~\"5..\"}[5m]))","validRequest":"sum(rate(http_requests_total[5m]))"}}`
+	latencyPath      = `{"latency":"histogram_quantile(0.99, sum(rate(http_request_duration_seconds_bucket[5m])) by (le))"}`
+)
+
+func sli(metricPath string) *models.SliBody {
+	return &models.SliBody{Id: 3, Name: "checkout", ServiceId: 7, MetricPath: metricPath}
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name      string
+		sli       *models.SliBody
+		sliType   string
+		slo       *models.SloBody
+		ratio1h   string
+		objective string
+		budget    string
+	}{
+		{
+			name:      "availability scales range selectors",
+			sli:       sli(availabilityPath),
+			sliType:   models.Types.Availability,
+			slo:       &models.SloBody{Id: 9, Name: "checkout-99.9", ObjectivePercentage: 99.9},
+			ratio1h:   `1 - ((sum(rate(http_requests_total{code!~"5.."}[1h]))) / (sum(rate(http_requests_total[1h]))))`,
+			objective: "0.999",
+			budget:    "0.001",
+		},
+		{
+			name:      "latency counts slices over the threshold",
+			sli:       sli(latencyPath),
+			sliType:   models.Types.Latency,
+			slo:       &models.SloBody{Id: 9, Name: "checkout-p99", ObjectivePercentage: 95, Threshold: 0.25},
+			ratio1h:   "avg_over_time(((histogram_quantile(0.99, sum(rate(http_request_duration_seconds_bucket[5m])) by (le))) > bool 0.25)[1h:1m])",
+			objective: "0.95",
+			budget:    "0.05",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := Generate(tt.sli, tt.sliType, []*models.SloBody{tt.slo}, "1m")
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			if len(file.Groups) != 2 {
+				t.Fatalf("Groups = %d, want a recording and an alert group", len(file.Groups))
+			}
+			recordings, alerts := file.Groups[0], file.Groups[1]
+			if recordings.Name != "slo-9-recordings" || alerts.Name != "slo-9-alerts" {
+				t.Errorf("group names = %s, %s", recordings.Name, alerts.Name)
+			}
+
+			records := map[string]*Rule{}
+			for _, r := range recordings.Rules {
+				records[r.Record] = r
+			}
+			if len(records) != len(Windows)+2 {
+				t.Errorf("recording rules = %d, want %d", len(records), len(Windows)+2)
+			}
+			for _, w := range Windows {
+				if records[errorRatioRecord+w] == nil {
+					t.Errorf("no error ratio recorded over %s", w)
+				}
+			}
+			if got := records[errorRatioRecord+"1h"]; got == nil || got.Expr != tt.ratio1h {
+				t.Errorf("1h error ratio = %+v, want %s", got, tt.ratio1h)
+			}
+			if got := records[objectiveRecord].Expr; got != tt.objective {
+				t.Errorf("objective = %s, want %s", got, tt.objective)
+			}
+			if got := records[errorBudgetRecord].Expr; got != tt.budget {
+				t.Errorf("error budget = %s, want %s", got, tt.budget)
+			}
+			wantLabels := map[string]string{"sli_id": "3", "sli": "checkout", "slo_id": "9", "slo": tt.slo.Name, "service_id": "7"}
+			for k, v := range wantLabels {
+				if got := records[objectiveRecord].Labels[k]; got != v {
+					t.Errorf("label %s = %q, want %q", k, got, v)
+				}
+			}
+
+			if len(alerts.Rules) != len(BurnRateAlerts) {
+				t.Fatalf("alerts = %d, want %d", len(alerts.Rules), len(BurnRateAlerts))
+			}
+			page := alerts.Rules[0]
+			if page.Alert != burnRateAlertName || page.Labels["severity"] != "page" || page.Labels["slo_id"] != "9" {
+				t.Errorf("page alert = %+v", page)
+			}
+			want := errorRatioRecord + `1h{slo_id="9"} > 14.4 * ` + tt.budget
+			if !strings.Contains(page.Expr, want) {
+				t.Errorf("page alert expr = %s, want it to contain %s", page.Expr, want)
+			}
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	slo := &models.SloBody{Id: 9, Name: "checkout", ObjectivePercentage: 99}
+	tests := []struct {
+		name    string
+		sli     *models.SliBody
+		sliType string
+		slos    []*models.SloBody
+	}{
+		{
+			name:    "no error ratio for the sli type",
+			sli:     sli(`{"throughput":"sum(rate(http_requests_total[5m]))"}`),
+			sliType: models.Types.Throughput,
+			slos:    []*models.SloBody{slo},
+		},
+		{
+			name:    "no slos",
+			sli:     sli(availabilityPath),
+			sliType: models.Types.Availability,
+		},
+		{
+			name:    "availability query without a range selector",
+			sli:     sli(`{"availability":{"goodRequest":"http_requests_good","validRequest":"http_requests_total"}}`),
+			sliType: models.Types.Availability,
+			slos:    []*models.SloBody{slo},
+		},
+		{
+			name:    "latency slo without a threshold",
+			sli:     sli(latencyPath),
+			sliType: models.Types.Latency,
+			slos:    []*models.SloBody{slo},
+		},
+		{
+			name:    "undecodable metric path",
+			sli:     sli(`{`),
+			sliType: models.Types.Availability,
+			slos:    []*models.SloBody{slo},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Generate(tt.sli, tt.sliType, tt.slos, "1m"); err == nil {
+				t.Error("Generate() error = nil, want an error")
+			}
+		})
+	}
+}
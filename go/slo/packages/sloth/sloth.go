@@ -0,0 +1,179 @@
+package sloth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/manifest"
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+	"gopkg.in/yaml.v3"
+)
+
+const Version = "prometheus/v1"
+
+// windowPlaceholder is replaced in Sloth queries by the range they are evaluated over
+var windowPlaceholder = regexp.MustCompile(`\{\{\s*\.window\s*\}\}`)
+
+// Spec is a Sloth prometheus/v1 SLO spec, alerting and labels have no Blameless counterpart
+type Spec struct {
+	Version string `yaml:"version"`
+	Service string `yaml:"service"`
+	Slos    []*Slo `yaml:"slos"`
+}
+
+type Slo struct {
+	Name        string  `yaml:"name"`
+	Objective   float64 `yaml:"objective"`
+	Description string  `yaml:"description"`
+	Sli         Sli     `yaml:"sli"`
+}
+
+type Sli struct {
+	Events *Events     `yaml:"events,omitempty"`
+	Raw    *Raw        `yaml:"raw,omitempty"`
+	Plugin interface{} `yaml:"plugin,omitempty"`
+}
+
+type Events struct {
+	ErrorQuery string `yaml:"errorQuery"`
+	TotalQuery string `yaml:"totalQuery"`
+}
+
+type Raw struct {
+	ErrorRatioQuery string `yaml:"errorRatioQuery"`
+}
+
+// Mapping resolves Sloth services to Blameless service IDs. Queries holds explicit good
+// and valid queries, keyed by <service>/<slo name>, that replace the derived ones.
+type Mapping struct {
+	Services map[string]int                        `yaml:"services"`
+	Queries  map[string]*models.AvailabilityStruct `yaml:"queries,omitempty"`
+}
+
+// LoadSpecs reads Sloth specs from a file, or every .yaml and .yml file under a directory
+func LoadSpecs(path string) ([]*Spec, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return loadFile(path)
+	}
+	specs := []*Spec{}
+	err = filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(p))
+		if d.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			return nil
+		}
+		fileSpecs, err := loadFile(p)
+		if err != nil {
+			return err
+		}
+		specs = append(specs, fileSpecs...)
+		return nil
+	})
+	return specs, err
+}
+
+func loadFile(path string) ([]*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	specs := []*Spec{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		spec := &Spec{}
+		err := dec.Decode(spec)
+		if errors.Is(err, io.EOF) {
+			return specs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %w", path, err)
+		}
+		if spec.Version != Version {
+			return nil, fmt.Errorf("%s has version %q, only Sloth %s specs are supported", path, spec.Version, Version)
+		}
+		specs = append(specs, spec)
+	}
+}
+
+func LoadMapping(path string) (*Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &Mapping{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(m); err != nil {
+		return nil, fmt.Errorf("unable to parse mapping %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Import translates Sloth SLOs into availability SLIs named <service>-<slo name>, each
+// with an SLO over the longest rolling window Blameless keeps. The good query is the
+// total query minus the error query unless the mapping gives explicit queries. window
+// replaces {{.window}} in the queries, usually the ingest step.
+func Import(specs []*Spec, mapping *Mapping, window string) (*manifest.Manifest, error) {
+	m := &manifest.Manifest{}
+	for _, spec := range specs {
+		serviceId, ok := mapping.Services[spec.Service]
+		if !ok {
+			return nil, fmt.Errorf("no Blameless service ID for Sloth service %q in the mapping file", spec.Service)
+		}
+		for _, slo := range spec.Slos {
+			queries, err := availabilityQueries(spec.Service, slo, mapping, window)
+			if err != nil {
+				return nil, err
+			}
+			name := fmt.Sprintf("%s-%s", spec.Service, slo.Name)
+			m.Slis = append(m.Slis, &manifest.Sli{
+				Name:        name,
+				Description: slo.Description,
+				ServiceId:   serviceId,
+				Type:        models.Types.Availability,
+				MetricPath:  &models.MetricPath{Availability: queries},
+				Slos: []*manifest.Slo{{
+					Name:                name,
+					Description:         slo.Description,
+					ObjectivePercentage: slo.Objective,
+					Window: &models.SloWindow{
+						Type: models.WindowTypes.Rolling,
+						Days: models.MaxRollingDays,
+					},
+				}},
+			})
+		}
+	}
+	return m, nil
+}
+
+func availabilityQueries(service string, slo *Slo, mapping *Mapping, window string) (*models.AvailabilityStruct, error) {
+	key := fmt.Sprintf("%s/%s", service, slo.Name)
+	if explicit, ok := mapping.Queries[key]; ok {
+		return &models.AvailabilityStruct{
+			GoodRequest:  windowPlaceholder.ReplaceAllLiteralString(explicit.GoodRequest, window),
+			ValidRequest: windowPlaceholder.ReplaceAllLiteralString(explicit.ValidRequest, window),
+		}, nil
+	}
+	if slo.Sli.Events == nil || slo.Sli.Events.ErrorQuery == "" || slo.Sli.Events.TotalQuery == "" {
+		return nil, fmt.Errorf("SLO %s has no errorQuery and totalQuery, give its good and valid queries in the mapping file under queries.%s", key, key)
+	}
+	total := strings.TrimSpace(windowPlaceholder.ReplaceAllLiteralString(slo.Sli.Events.TotalQuery, window))
+	errorQuery := strings.TrimSpace(windowPlaceholder.ReplaceAllLiteralString(slo.Sli.Events.ErrorQuery, window))
+	return &models.AvailabilityStruct{
+		GoodRequest:  fmt.Sprintf("(%s) - (%s)", total, errorQuery),
+		ValidRequest: total,
+	}, nil
+}
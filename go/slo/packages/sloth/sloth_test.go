@@ -0,0 +1,160 @@
+package sloth
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/blamelesshq/blameless-examples/slo/packages/models"
+)
+
+func eventsSlo(name string) *Slo {
+	return &Slo{
+		Name:        name,
+		Objective:   99.9,
+		Description: "Requests without server errors",
+		Sli: Sli{Events: &Events{
+			ErrorQuery: `sum(rate(http_requests_total{code=~"5.."}[{{.window}}]))`,
+			TotalQuery: " sum(rate(http_requests_total[{{ .window }}])) ",
+		}},
+	}
+}
+
+func TestImport(t *testing.T) {
+	rawSlo := &Slo{Name: "latency", Objective: 99, Sli: Sli{Raw: &Raw{ErrorRatioQuery: "slow_ratio"}}}
+	tests := []struct {
+		name    string
+		specs   []*Spec
+		mapping *Mapping
+		want    map[string]*models.AvailabilityStruct // By SLI name
+	}{
+		{
+			name:    "good query is total minus errors",
+			specs:   []*Spec{{Version: Version, Service: "checkout", Slos: []*Slo{eventsSlo("requests")}}},
+			mapping: &Mapping{Services: map[string]int{"checkout": 7}},
+			want: map[string]*models.AvailabilityStruct{
+				"checkout-requests": {
+					GoodRequest:  `(sum(rate(http_requests_total[1m]))) - (sum(rate(http_requests_total{code=~"5.."}[1m])))`,
+					ValidRequest: "sum(rate(http_requests_total[1m]))",
+				},
+			},
+		},
+		{
+			name:  "mapping queries replace derived ones",
+			specs: []*Spec{{Version: Version, Service: "checkout", Slos: []*Slo{eventsSlo("requests"), rawSlo}}},
+			mapping: &Mapping{
+				Services: map[string]int{"checkout": 7},
+				Queries: map[string]*models.AvailabilityStruct{
+					"checkout/latency": {GoodRequest: "sum(rate(fast_total[{{.window}}]))", ValidRequest: "sum(rate(all_total[{{.window}}]))"},
+				},
+			},
+			want: map[string]*models.AvailabilityStruct{
+				"checkout-requests": {
+					GoodRequest:  `(sum(rate(http_requests_total[1m]))) - (sum(rate(http_requests_total{code=~"5.."}[1m])))`,
+					ValidRequest: "sum(rate(http_requests_total[1m]))",
+				},
+				"checkout-latency": {GoodRequest: "sum(rate(fast_total[1m]))", ValidRequest: "sum(rate(all_total[1m]))"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Import(tt.specs, tt.mapping, "1m")
+			if err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+			if len(m.Slis) != len(tt.want) {
+				t.Fatalf("Slis = %d, want %d", len(m.Slis), len(tt.want))
+			}
+			for _, sli := range m.Slis {
+				want, ok := tt.want[sli.Name]
+				if !ok {
+					t.Errorf("unexpected SLI %s", sli.Name)
+					continue
+				}
+				if sli.ServiceId != 7 || sli.Type != models.Types.Availability {
+					t.Errorf("%s service, type = %d, %s, want 7, %s", sli.Name, sli.ServiceId, sli.Type, models.Types.Availability)
+				}
+				if !reflect.DeepEqual(sli.MetricPath.Availability, want) {
+					t.Errorf("%s queries = %+v, want %+v", sli.Name, sli.MetricPath.Availability, want)
+				}
+				if len(sli.Slos) != 1 {
+					t.Fatalf("%s SLOs = %d, want 1", sli.Name, len(sli.Slos))
+				}
+				slo := sli.Slos[0]
+				wantWindow := &models.SloWindow{Type: models.WindowTypes.Rolling, Days: models.MaxRollingDays}
+				if slo.Name != sli.Name || !reflect.DeepEqual(slo.Window, wantWindow) {
+					t.Errorf("%s SLO = %+v, window %+v", sli.Name, slo, slo.Window)
+				}
+			}
+		})
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []*Spec
+		mapping *Mapping
+	}{
+		{
+			name:    "service missing from the mapping",
+			specs:   []*Spec{{Version: Version, Service: "checkout", Slos: []*Slo{eventsSlo("requests")}}},
+			mapping: &Mapping{Services: map[string]int{"search": 8}},
+		},
+		{
+			name:    "raw sli without mapping queries",
+			specs:   []*Spec{{Version: Version, Service: "checkout", Slos: []*Slo{{Name: "latency", Sli: Sli{Raw: &Raw{ErrorRatioQuery: "slow_ratio"}}}}}},
+			mapping: &Mapping{Services: map[string]int{"checkout": 7}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Import(tt.specs, tt.mapping, "1m"); err == nil {
+				t.Error("Import() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestLoadSpecs(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		services int
+		wantErr  bool
+	}{
+		{
+			name:     "documents of every yaml file",
+			files:    map[string]string{"a.yaml": "version: prometheus/v1\nservice: a\n---\nversion: prometheus/v1\nservice: b\n", "nested/c.yml": "version: prometheus/v1\nservice: c\nslos: []\n", "notes.txt": "ignored"},
+			services: 3,
+		},
+		{
+			name:    "other spec versions",
+			files:   map[string]string{"a.yaml": "version: sloth.slok.dev/v1\nservice: a\n"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			specs, err := LoadSpecs(dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadSpecs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(specs) != tt.services {
+				t.Errorf("LoadSpecs() = %d specs, want %d", len(specs), tt.services)
+			}
+		})
+	}
+}
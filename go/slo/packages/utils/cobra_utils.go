@@ -56,7 +56,7 @@ func StringPrompt(label string) string {
 // BooleanPrompt provides you a simple interface to execute boolean prompt request
 func BooleanPrompt(label string) bool {
 	validateBool := func(input string) error {
-		if input != "true" || input != "false" {
+		if input != "true" && input != "false" {
 			return fmt.Errorf("must provide either true or false for %s", label)
 		}
 		return nil
@@ -75,3 +75,104 @@ func BooleanPrompt(label string) bool {
 	}
 	return bl
 }
+
+// StringPromptDefault provides you a string prompt prefilled with the current value
+func StringPromptDefault(label string, current string) string {
+	stringP := promptui.Prompt{
+		Label:     label,
+		Default:   current,
+		AllowEdit: true,
+	}
+	result, err := stringP.Run()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return result
+}
+
+// IntPromptDefault provides you an integer prompt prefilled with the current value
+func IntPromptDefault(label string, current int) int {
+	validateInt := func(input string) error {
+		_, err := strconv.Atoi(input)
+		if err != nil {
+			return fmt.Errorf("unable to parse integer for %s", label)
+		}
+		return nil
+	}
+	intP := promptui.Prompt{
+		Label:     label,
+		Default:   strconv.Itoa(current),
+		AllowEdit: true,
+		Validate:  validateInt,
+	}
+	result, err := intP.Run()
+	if err != nil {
+		log.Fatal(err)
+	}
+	id, err := strconv.Atoi(result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return id
+}
+
+// ConfirmPrompt asks a yes or no question, anything but yes is a no
+func ConfirmPrompt(label string) bool {
+	confirmP := promptui.Prompt{
+		Label:     label,
+		IsConfirm: true,
+	}
+	_, err := confirmP.Run()
+	return err == nil
+}
+
+// FloatPrompt provides you a simple interface to execute decimal prompt request
+func FloatPrompt(label string) float64 {
+	return FloatPromptDefault(label, "")
+}
+
+// FloatPromptDefault provides you a decimal prompt prefilled with the current value
+func FloatPromptDefault(label string, current string) float64 {
+	validateFloat := func(input string) error {
+		_, err := strconv.ParseFloat(input, 64)
+		if err != nil {
+			return fmt.Errorf("unable to parse number for %s", label)
+		}
+		return nil
+	}
+	floatP := promptui.Prompt{
+		Label:     label,
+		Default:   current,
+		AllowEdit: current != "",
+		Validate:  validateFloat,
+	}
+	result, err := floatP.Run()
+	if err != nil {
+		log.Fatal(err)
+	}
+	f, err := strconv.ParseFloat(result, 64)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return f
+}
+
+// SelectPrompt lets you pick one of a fixed list of values
+func SelectPrompt(label string, items []string, current string) string {
+	cursor := 0
+	for i, item := range items {
+		if item == current {
+			cursor = i
+		}
+	}
+	selectP := promptui.Select{
+		Label:     label,
+		Items:     items,
+		CursorPos: cursor,
+	}
+	_, result, err := selectP.Run()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return result
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var durationPattern = regexp.MustCompile(`^(\d+)([smhdw])$`)
+
+var durationUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// ParseDuration reads a duration such as 30m, 6h, 1d or 2w. Unlike time.ParseDuration
+// it takes days and weeks, the units SLO windows and lookbacks are given in.
+func ParseDuration(s string) (time.Duration, error) {
+	m := durationPattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid duration %q, use a number and one of s, m, h, d or w", s)
+	}
+	n, _ := strconv.Atoi(m[1])
+	return time.Duration(n) * durationUnits[m[2]], nil
+}
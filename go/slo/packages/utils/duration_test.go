@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "45s", want: 45 * time.Second},
+		{in: "30m", want: 30 * time.Minute},
+		{in: "6h", want: 6 * time.Hour},
+		{in: "3d", want: 72 * time.Hour},
+		{in: "2w", want: 14 * 24 * time.Hour},
+		{in: "1y", wantErr: true},
+		{in: "h", wantErr: true},
+		{in: "1h30m", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseDuration(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDuration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
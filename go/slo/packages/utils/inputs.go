@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+// Inputs resolve a command value from its flag first. A flag whose default was read
+// from config counts as set. Only then is the user prompted, and only when stdin is
+// a terminal, so scripts and CI fail with the flag to pass instead of hanging.
+
+// IsInteractive reports whether stdin is a terminal that prompts can read from
+func IsInteractive() bool {
+	return isatty.IsTerminal(os.Stdin.Fd())
+}
+
+func requireInteractive(flag string, label string) {
+	if !IsInteractive() {
+		log.Fatalf("missing %s, pass --%s when not running in a terminal", label, flag)
+	}
+}
+
+// IntInput reads an integer flag, prompting for it when it is unset
+func IntInput(cmd *cobra.Command, flag string, label string) int {
+	v, err := cmd.Flags().GetInt(flag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cmd.Flags().Changed(flag) || v != 0 {
+		return v
+	}
+	requireInteractive(flag, label)
+	return IntPrompt(label)
+}
+
+// StringInput reads a string flag, prompting for it when it is unset
+func StringInput(cmd *cobra.Command, flag string, label string) string {
+	v, err := cmd.Flags().GetString(flag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cmd.Flags().Changed(flag) || v != "" {
+		return v
+	}
+	requireInteractive(flag, label)
+	return StringPrompt(label)
+}
+
+// FloatInput reads a decimal flag, prompting for it when it is unset
+func FloatInput(cmd *cobra.Command, flag string, label string) float64 {
+	v, err := cmd.Flags().GetFloat64(flag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cmd.Flags().Changed(flag) || v != 0 {
+		return v
+	}
+	requireInteractive(flag, label)
+	return FloatPrompt(label)
+}
+
+// IntInputDefault reads an integer flag for an update. Without the flag the user may
+// edit current in a terminal, otherwise current is kept.
+func IntInputDefault(cmd *cobra.Command, flag string, label string, current int) int {
+	if cmd.Flags().Changed(flag) {
+		v, err := cmd.Flags().GetInt(flag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return v
+	}
+	if !IsInteractive() {
+		return current
+	}
+	return IntPromptDefault(label, current)
+}
+
+// StringInputDefault reads a string flag for an update, see IntInputDefault
+func StringInputDefault(cmd *cobra.Command, flag string, label string, current string) string {
+	if cmd.Flags().Changed(flag) {
+		v, err := cmd.Flags().GetString(flag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return v
+	}
+	if !IsInteractive() {
+		return current
+	}
+	return StringPromptDefault(label, current)
+}
+
+// FloatInputDefault reads a decimal flag for an update, see IntInputDefault
+func FloatInputDefault(cmd *cobra.Command, flag string, label string, current float64) float64 {
+	if cmd.Flags().Changed(flag) {
+		v, err := cmd.Flags().GetFloat64(flag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return v
+	}
+	if !IsInteractive() {
+		return current
+	}
+	c := ""
+	if current != 0 {
+		c = strconv.FormatFloat(current, 'f', -1, 64)
+	}
+	return FloatPromptDefault(label, c)
+}
+
+// SelectInput reads a flag that must be one of items, offering a selection when it is
+// unset. An unset flag keeps current outside a terminal, or fails if there is none.
+func SelectInput(cmd *cobra.Command, flag string, label string, items []string, current string) string {
+	v, err := cmd.Flags().GetString(flag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cmd.Flags().Changed(flag) || v != "" {
+		for _, item := range items {
+			if item == v {
+				return v
+			}
+		}
+		log.Fatalf("--%s must be one of %v, got %q", flag, items, v)
+	}
+	if !IsInteractive() && current != "" {
+		return current
+	}
+	requireInteractive(flag, label)
+	return SelectPrompt(label, items, current)
+}
+
+// ConfirmInput asks for confirmation unless the bool flag, usually --yes, is set
+func ConfirmInput(cmd *cobra.Command, flag string, label string) bool {
+	yes, err := cmd.Flags().GetBool(flag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if yes {
+		return true
+	}
+	requireInteractive(flag, label)
+	return ConfirmPrompt(label)
+}